@@ -0,0 +1,70 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dexon-foundation/dexon-consensus/core/db"
+	"github.com/dexon-foundation/dexon-consensus/core/types"
+)
+
+// dumpDB prints blocks persisted by a simulation node, in delivery order.
+// Simulation nodes persist their database through db.MemBackedDB, so the
+// path is the file written by it when the node stops.
+func dumpDB(path string, w io.Writer) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	dbInst, err := db.NewMemBackedDB(path)
+	if err != nil {
+		return err
+	}
+	iter, err := dbInst.GetAllBlocks()
+	if err != nil {
+		return err
+	}
+	blocks := []types.Block{}
+	for {
+		b, err := iter.NextBlock()
+		if err != nil {
+			if err == db.ErrIterationFinished {
+				break
+			}
+			return err
+		}
+		blocks = append(blocks, b)
+	}
+	// Blocks are delivered by position on the single chain.
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Position.Older(blocks[j].Position)
+	})
+	for _, b := range blocks {
+		if _, err := fmt.Fprintf(w, "%s round:%d height:%d timestamp:%s witness:%d\n",
+			b.Hash.String(), b.Position.Round, b.Position.Height,
+			b.Timestamp.UTC().Format(time.RFC3339Nano),
+			b.Witness.Height); err != nil {
+			return err
+		}
+	}
+	return nil
+}
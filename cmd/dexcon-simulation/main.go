@@ -38,9 +38,17 @@ var configFile = flag.String("config", "", "path to simulation config file")
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to `file`")
 var memprofile = flag.String("memprofile", "", "write memory profile to `file`")
 var logfile = flag.String("log", "", "write log to `file`-nodeID.log")
+var dump = flag.String("dump", "", "dump blocks in simulation database `file` and exit")
 
 func main() {
 	flag.Parse()
+	if *dump != "" {
+		if err := dumpDB(*dump, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	rand.Seed(time.Now().UnixNano())
 	// Supports runtime pprof monitoring.
 	go func() {
@@ -45,6 +45,55 @@ var (
 const maxResultCache = 100
 const settingLimit = 3
 
+// baLatencyBuckets are upper bounds of buckets of BA latency histogram.
+var baLatencyBuckets = []time.Duration{
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2 * time.Second,
+	4 * time.Second,
+	8 * time.Second,
+	16 * time.Second,
+}
+
+// LatencyHistogram is the distribution of latencies. Counts[i] is the count
+// of latencies not greater than Buckets[i], and the last element of Counts is
+// the count of latencies greater than all buckets.
+type LatencyHistogram struct {
+	Buckets []time.Duration
+	Counts  []uint64
+	Total   uint64
+	Sum     time.Duration
+}
+
+func newLatencyHistogram(buckets []time.Duration) LatencyHistogram {
+	return LatencyHistogram{
+		Buckets: buckets,
+		Counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+func (h *LatencyHistogram) add(latency time.Duration) {
+	idx := len(h.Buckets)
+	for i, bound := range h.Buckets {
+		if latency <= bound {
+			idx = i
+			break
+		}
+	}
+	h.Counts[idx]++
+	h.Total++
+	h.Sum += latency
+}
+
+// Clone returns a copied histogram.
+func (h *LatencyHistogram) Clone() LatencyHistogram {
+	copied := *h
+	copied.Buckets = append([]time.Duration(nil), h.Buckets...)
+	copied.Counts = append([]uint64(nil), h.Counts...)
+	return copied
+}
+
 // genValidLeader generate a validLeader function for agreement modules.
 func genValidLeader(
 	mgr *agreementMgr) validLeaderFn {
@@ -138,6 +187,8 @@ type agreementMgr struct {
 	waitGroup         sync.WaitGroup
 	isRunning         bool
 	lock              sync.RWMutex
+	baLatencyLock     sync.Mutex
+	baLatency         LatencyHistogram
 }
 
 func newAgreementMgr(con *Consensus) (mgr *agreementMgr, err error) {
@@ -156,6 +207,7 @@ func newAgreementMgr(con *Consensus) (mgr *agreementMgr, err error) {
 		processedBAResult: make(map[types.Position]struct{}, maxResultCache),
 		voteFilter:        utils.NewVoteFilter(),
 		settingCache:      settingCache,
+		baLatency:         newLatencyHistogram(baLatencyBuckets),
 	}
 	mgr.recv = &consensusBAReceiver{
 		consensus:     con,
@@ -225,6 +277,19 @@ func (mgr *agreementMgr) calcLeader(
 	return types.NodeID{}, ErrNoValidLeader
 }
 
+// proposalBatchWindow returns the batching window of proposed blocks in that
+// round, it's capped at half of lambdaBA to avoid timing out BA of others.
+func (mgr *agreementMgr) proposalBatchWindow(round uint64) time.Duration {
+	if !mgr.recv.isNotary {
+		return 0
+	}
+	window := mgr.con.proposalBatchWindow
+	if max := mgr.config(round).lambdaBA / 2; window > max {
+		window = max
+	}
+	return window
+}
+
 func (mgr *agreementMgr) config(round uint64) *agreementMgrConfig {
 	mgr.lock.RLock()
 	defer mgr.lock.RUnlock()
@@ -533,11 +598,26 @@ Loop:
 	}
 }
 
+func (mgr *agreementMgr) recordBALatency(latency time.Duration) {
+	mgr.baLatencyLock.Lock()
+	defer mgr.baLatencyLock.Unlock()
+	mgr.baLatency.add(latency)
+}
+
+func (mgr *agreementMgr) baLatencies() LatencyHistogram {
+	mgr.baLatencyLock.Lock()
+	defer mgr.baLatencyLock.Unlock()
+	return mgr.baLatency.Clone()
+}
+
 func (mgr *agreementMgr) baRoutineForOneRound(
 	setting *baRoundSetting) (err error) {
 	agr := mgr.baModule
 	recv := mgr.recv
 	oldPos := agr.agreementID()
+	// The time when BA is restarted for the current position, it's reset once
+	// the latency is recorded.
+	var restartedAt time.Time
 	restart := func(restartPos types.Position) (breakLoop bool, err error) {
 		if !isStop(restartPos) {
 			if restartPos.Height+1 >= mgr.config(setting.round).RoundEndHeight() {
@@ -601,8 +681,19 @@ func (mgr *agreementMgr) baRoutineForOneRound(
 			return
 		}
 		time.Sleep(nextTime.Sub(time.Now()))
+		// Payloads are accumulated before restarting BA, thus votes could
+		// still be processed while waiting.
+		if window := mgr.proposalBatchWindow(setting.round); window > 0 {
+			select {
+			case <-mgr.ctx.Done():
+				breakLoop = true
+				return
+			case <-time.After(window):
+			}
+		}
 		setting.ticker.Restart()
 		agr.restart(setting.dkgSet, setting.threshold, nextPos, leader, setting.crs)
+		restartedAt = time.Now()
 		return
 	}
 Loop:
@@ -612,7 +703,14 @@ Loop:
 			break Loop
 		default:
 		}
+		if !mgr.con.waitResumed() {
+			break Loop
+		}
 		if agr.confirmed() {
+			if !restartedAt.IsZero() {
+				mgr.recordBALatency(time.Since(restartedAt))
+				restartedAt = time.Time{}
+			}
 			// Block until receive restartPos
 			select {
 			case restartPos := <-recv.restartNotary:
@@ -44,6 +44,28 @@ const (
 	stateSleep
 )
 
+func (t agreementStateType) String() string {
+	switch t {
+	case stateFast:
+		return "fast"
+	case stateFastVote:
+		return "fast-vote"
+	case stateInitial:
+		return "initial"
+	case statePreCommit:
+		return "pre-commit"
+	case stateCommit:
+		return "commit"
+	case stateForward:
+		return "forward"
+	case statePullVote:
+		return "pull-vote"
+	case stateSleep:
+		return "sleep"
+	}
+	return fmt.Sprintf("unknown(%d)", int(t))
+}
+
 type agreementState interface {
 	state() agreementStateType
 	nextState() (agreementState, error)
@@ -88,6 +88,29 @@ type agreementReceiver interface {
 	VerifyPartialSignature(vote *types.Vote) (bool, bool)
 }
 
+// AgreementState is a snapshot of the agreement module for diagnosis.
+type AgreementState struct {
+	// Position is the position this agreement is working on.
+	Position types.Position
+	// Leader is the leader of this position.
+	Leader types.NodeID
+	// Stopped is true when the agreement is not running on any position.
+	Stopped bool
+	// State is the name of current state of the agreement.
+	State string
+	// Period is the current period.
+	Period uint64
+	// Clocks is how many clocks current state requires.
+	Clocks int
+	// VoteCount is the count of votes collected in current period.
+	VoteCount int
+	// PullingVotes is true when the agreement requires more votes to continue.
+	PullingVotes bool
+	// Confirmed is true when a block is confirmed and the agreement is waiting
+	// for restarting on next position.
+	Confirmed bool
+}
+
 type pendingBlock struct {
 	block        *types.Block
 	receivedTime time.Time
@@ -329,6 +352,26 @@ func (a *agreement) pullVotes() bool {
 		(a.state.state() == statePreCommit && (a.data.period%3) == 0)
 }
 
+// snapshot returns the current state of this agreement.
+func (a *agreement) snapshot() (s AgreementState) {
+	s.Position = a.agreementID()
+	s.Leader = a.leader()
+	s.Stopped = isStop(s.Position)
+	s.Clocks = a.clocks()
+	s.PullingVotes = a.pullVotes()
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	a.data.lock.RLock()
+	defer a.data.lock.RUnlock()
+	s.State = a.state.state().String()
+	s.Period = a.data.period
+	s.Confirmed = a.hasOutput
+	for _, votes := range a.data.votes[a.data.period] {
+		s.VoteCount += len(votes)
+	}
+	return
+}
+
 // agreementID returns the current agreementID.
 func (a *agreement) agreementID() types.Position {
 	return a.aID.Load().(struct {
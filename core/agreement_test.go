@@ -246,6 +246,22 @@ func (s *AgreementTestSuite) TestSimpleConfirm() {
 	s.Equal(blockHash, confirmBlock)
 }
 
+func (s *AgreementTestSuite) TestSnapshot() {
+	a, leaderNode := s.newAgreement(4, 0, s.defaultValidLeader)
+	state := a.snapshot()
+	s.Equal(s.agreementID, state.Position)
+	s.Equal(leaderNode, state.Leader)
+	s.False(state.Stopped)
+	s.Equal("fast", state.State)
+	s.Equal(uint64(2), state.Period)
+	s.Equal(0, state.VoteCount)
+	s.False(state.Confirmed)
+	a.nextState()
+	s.Equal("fast-vote", a.snapshot().State)
+	a.stop()
+	s.True(a.snapshot().Stopped)
+}
+
 func (s *AgreementTestSuite) TestPartitionOnCommitVote() {
 	a, _ := s.newAgreement(4, -1, s.defaultValidLeader)
 	// FastState
@@ -564,6 +580,26 @@ func (s *AgreementTestSuite) TestFindBlockInPendingSet() {
 	s.Require().NotNil(block)
 }
 
+func (s *AgreementTestSuite) TestRestartAcrossRound() {
+	a, leaderNode := s.newAgreement(4, 0, s.defaultValidLeader)
+	block := s.proposeBlock(leaderNode, a.data.leader.hashCRS, []byte{})
+	s.Require().NoError(a.processBlock(block))
+	_, exist := a.findCandidateBlockNoLock(block.Hash)
+	s.Require().True(exist)
+	// Candidate blocks and votes from previous round should not be served
+	// after restarting for the next round.
+	a.restart(a.notarySet, utils.GetBAThreshold(&types.Config{
+		NotarySetSize: uint32(len(a.notarySet)),
+	}), types.Position{Round: 1, Height: types.GenesisHeight + 1}, leaderNode,
+		common.NewRandomHash())
+	_, exist = a.findCandidateBlockNoLock(block.Hash)
+	s.Require().False(exist)
+	_, exist = a.findBlockNoLock(block.Hash)
+	s.Require().False(exist)
+	s.Require().Empty(a.data.blocks)
+	s.Require().Len(a.data.votes, 1)
+}
+
 func (s *AgreementTestSuite) TestConfirmWithBlock() {
 	a, _ := s.newAgreement(4, -1, s.defaultValidLeader)
 	block := &types.Block{
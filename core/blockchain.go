@@ -41,6 +41,8 @@ var (
 	ErrInvalidBlockHeight       = errors.New("invalid block height")
 	ErrInvalidRoundID           = errors.New("invalid round id")
 	ErrInvalidTimestamp         = errors.New("invalid timestamp")
+	ErrBlockFromFuture          = errors.New("block from future")
+	ErrBlockPayloadTooLarge     = errors.New("block payload too large")
 	ErrNotFollowTipPosition     = errors.New("not follow tip position")
 	ErrDuplicatedPendingBlock   = errors.New("duplicated pending block")
 	ErrRetrySanityCheckLater    = errors.New("retry sanity check later")
@@ -111,13 +113,32 @@ type blockChainConfig struct {
 	utils.RoundBasedConfig
 
 	minBlockInterval time.Duration
+	maxClockSkew     time.Duration
+	maxPayloadSize   uint64
 }
 
 func (c *blockChainConfig) fromConfig(round uint64, config *types.Config) {
 	c.minBlockInterval = config.MinBlockInterval
+	c.maxClockSkew = config.MaxClockSkew
+	c.maxPayloadSize = config.MaxBlockPayloadSize
 	c.SetupRoundBasedFields(round, config)
 }
 
+// isFromFuture checks if the timestamp of a block is ahead of local clock by
+// more than maxClockSkew. The check is skipped when maxClockSkew is zero.
+func (c *blockChainConfig) isFromFuture(b *types.Block, now time.Time) bool {
+	if c.maxClockSkew == 0 {
+		return false
+	}
+	return b.Timestamp.After(now.Add(c.maxClockSkew))
+}
+
+// isPayloadTooLarge checks if the payload of a block exceeds maxPayloadSize.
+// The check is skipped when maxPayloadSize is zero.
+func (c *blockChainConfig) isPayloadTooLarge(b *types.Block) bool {
+	return c.maxPayloadSize > 0 && uint64(len(b.Payload)) > c.maxPayloadSize
+}
+
 func newBlockChainConfig(prev blockChainConfig, config *types.Config) (
 	c blockChainConfig) {
 	c = blockChainConfig{}
@@ -145,6 +166,8 @@ type blockChain struct {
 	pendingBlocks       pendingBlockRecords
 	confirmedBlocks     types.BlocksByPosition
 	dMoment             time.Time
+	// now returns the local clock, it's replaceable for testing.
+	now func() time.Time
 
 	// Do not access this variable besides processAgreementResult.
 	lastPosition types.Position
@@ -162,6 +185,7 @@ func newBlockChain(nID types.NodeID, dMoment time.Time, initBlock *types.Block,
 		app:           app,
 		logger:        logger,
 		dMoment:       dMoment,
+		now:           func() time.Time { return time.Now().UTC() },
 		pendingRandomnesses: make(
 			map[types.Position][]byte),
 	}
@@ -252,7 +276,10 @@ func (bc *blockChain) sanityCheck(b *types.Block) error {
 		if b.Timestamp.Before(bc.dMoment.Add(bc.configs[0].minBlockInterval)) {
 			return ErrInvalidTimestamp
 		}
-		return nil
+		if bc.configs[0].isFromFuture(b, bc.now()) {
+			return ErrBlockFromFuture
+		}
+		return utils.VerifyBlockSignature(b)
 	}
 	if b.IsGenesis() {
 		return ErrIsGenesisBlock
@@ -276,10 +303,18 @@ func (bc *blockChain) sanityCheck(b *types.Block) error {
 	if !b.ParentHash.Equal(bc.lastConfirmed.Hash) {
 		return ErrIncorrectParentHash
 	}
+	// Timestamps should be strictly increasing even when minBlockInterval is
+	// zero.
+	if !b.Timestamp.After(bc.lastConfirmed.Timestamp) {
+		return ErrInvalidTimestamp
+	}
 	if b.Timestamp.Before(bc.lastConfirmed.Timestamp.Add(
 		tipConfig.minBlockInterval)) {
 		return ErrInvalidTimestamp
 	}
+	if tipConfig.isFromFuture(b, bc.now()) {
+		return ErrBlockFromFuture
+	}
 	if err := utils.VerifyBlockSignature(b); err != nil {
 		return err
 	}
@@ -365,6 +400,19 @@ func (bc *blockChain) tipRound() uint64 {
 	return bc.lastConfirmed.Position.Round + offset
 }
 
+// roundOfHeight returns the round of a height by round configurations
+// notified so far.
+func (bc *blockChain) roundOfHeight(h uint64) (uint64, bool) {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	for _, c := range bc.configs {
+		if c.Contains(h) {
+			return c.RoundID(), true
+		}
+	}
+	return 0, false
+}
+
 func (bc *blockChain) confirmed(h uint64) bool {
 	bc.lock.RLock()
 	defer bc.lock.RUnlock()
@@ -425,6 +473,21 @@ func (bc *blockChain) lastDeliveredBlock() *types.Block {
 	return bc.lastDelivered
 }
 
+// deliveredTip returns the height, hash and a copy of the witness of the last
+// delivered block, taken under the same lock.
+func (bc *blockChain) deliveredTip() (uint64, common.Hash, types.Witness) {
+	bc.lock.RLock()
+	defer bc.lock.RUnlock()
+	if bc.lastDelivered == nil {
+		return 0, common.Hash{}, types.Witness{}
+	}
+	return bc.lastDelivered.Position.Height, bc.lastDelivered.Hash,
+		types.Witness{
+			Height: bc.lastDelivered.Witness.Height,
+			Data:   common.CopyBytes(bc.lastDelivered.Witness.Data),
+		}
+}
+
 func (bc *blockChain) lastPendingBlock() *types.Block {
 	bc.lock.RLock()
 	defer bc.lock.RUnlock()
@@ -544,6 +607,14 @@ func (bc *blockChain) prepareBlock(position types.Position,
 				b = nil
 				return
 			}
+			if bc.configs[0].isPayloadTooLarge(b) {
+				bc.logger.Error("Prepared payload is too large",
+					"position", &b.Position,
+					"size", len(b.Payload),
+					"max", bc.configs[0].maxPayloadSize)
+				b, err = nil, ErrBlockPayloadTooLarge
+				return
+			}
 			bc.logger.Debug("Calling genesis Application.PrepareWitness")
 			if b.Witness, err = bc.app.PrepareWitness(0); err != nil {
 				b = nil
@@ -579,6 +650,14 @@ func (bc *blockChain) prepareBlock(position types.Position,
 				b = nil
 				return
 			}
+			if tipConfig.isPayloadTooLarge(b) {
+				bc.logger.Error("Prepared payload is too large",
+					"position", &b.Position,
+					"size", len(b.Payload),
+					"max", tipConfig.maxPayloadSize)
+				b, err = nil, ErrBlockPayloadTooLarge
+				return
+			}
 			bc.logger.Debug("Calling Application.PrepareWitness",
 				"height", tip.Witness.Height)
 			if b.Witness, err = bc.app.PrepareWitness(
@@ -595,6 +674,11 @@ func (bc *blockChain) prepareBlock(position types.Position,
 			copy(b.Witness.Data, tip.Witness.Data)
 			b.Timestamp = minExpectedTime
 		}
+		// Empty blocks should also be strictly after the tip when
+		// minBlockInterval is zero.
+		if !b.Timestamp.After(tip.Timestamp) {
+			b.Timestamp = tip.Timestamp.Add(time.Nanosecond)
+		}
 	}
 	if empty {
 		if b.Hash, err = utils.HashBlock(b); err != nil {
@@ -47,6 +47,15 @@ func (t *testTSigVerifierGetter) UpdateAndGet(round uint64) (
 
 func (t *testTSigVerifierGetter) Purge(_ uint64) {}
 
+type testPayloadApp struct {
+	*test.App
+	payload []byte
+}
+
+func (app *testPayloadApp) PreparePayload(types.Position) ([]byte, error) {
+	return app.payload, nil
+}
+
 type BlockChainTestSuite struct {
 	suite.Suite
 
@@ -356,6 +365,87 @@ func (s *BlockChainTestSuite) TestSanityCheck() {
 	s.Require().NoError(bc.sanityCheck(b4))
 }
 
+func (s *BlockChainTestSuite) TestSanityCheckTimestampMonotonic() {
+	// Blocks with timestamps not after their parents' should be rejected
+	// even when minimum block interval is zero.
+	blockInterval := s.blockInterval
+	s.blockInterval = 0
+	defer func() { s.blockInterval = blockInterval }()
+	bc := s.newBlockChain(nil, 4)
+	b0 := s.newBlocks(1, nil)[0]
+	s.Require().NoError(bc.addBlock(b0))
+	s.Require().EqualError(ErrInvalidTimestamp,
+		bc.sanityCheck(s.newBlock(b0, 0, 0)).Error())
+	s.Require().EqualError(ErrInvalidTimestamp,
+		bc.sanityCheck(s.newBlock(b0, 0, -1*time.Second)).Error())
+	s.Require().NoError(bc.sanityCheck(s.newBlock(b0, 0, time.Nanosecond)))
+	// Proposed blocks should follow the same rule.
+	b1, err := bc.prepareBlock(
+		types.Position{Height: b0.Position.Height + 1}, b0.Timestamp, false)
+	s.Require().NoError(err)
+	s.Require().True(b1.Timestamp.After(b0.Timestamp))
+	// So do empty blocks.
+	b1, err = bc.addEmptyBlock(
+		types.Position{Height: b0.Position.Height + 1})
+	s.Require().NoError(err)
+	s.Require().True(b1.Timestamp.After(b0.Timestamp))
+	b2, err := bc.addEmptyBlock(
+		types.Position{Height: b1.Position.Height + 1})
+	s.Require().NoError(err)
+	s.Require().True(b2.Timestamp.After(b1.Timestamp))
+}
+
+func (s *BlockChainTestSuite) TestSanityCheckMalformedGenesis() {
+	bc := s.newBlockChain(nil, 4)
+	b0 := s.newBlocks(1, nil)[0]
+	// A block at genesis height with non-zero parent hash is not a genesis
+	// block.
+	malformed := *b0
+	malformed.ParentHash = common.NewRandomHash()
+	s.Require().NoError(s.signer.SignBlock(&malformed))
+	s.Require().False(malformed.IsGenesis())
+	s.Require().EqualError(
+		ErrNotGenesisBlock, bc.sanityCheck(&malformed).Error())
+	// Genesis block is allowed to carry payload.
+	withPayload := *b0
+	withPayload.Payload = []byte("payload")
+	s.Require().NoError(s.signer.SignBlock(&withPayload))
+	s.Require().NoError(bc.sanityCheck(&withPayload))
+	s.Require().NoError(bc.addBlock(b0))
+	// Once genesis block is confirmed, the malformed one is at an invalid
+	// height.
+	s.Require().EqualError(
+		ErrInvalidBlockHeight, bc.sanityCheck(&malformed).Error())
+}
+
+func (s *BlockChainTestSuite) TestSanityCheckClockSkew() {
+	bc := s.newBlockChain(nil, 10)
+	bc.configs[0].maxClockSkew = 10 * time.Second
+	blocks := s.newBlocks(2, nil)
+	b0, b1 := blocks[0], blocks[1]
+	now := b0.Timestamp
+	bc.now = func() time.Time { return now }
+	// Genesis block from future.
+	future := *b0
+	future.Timestamp = now.Add(time.Minute)
+	s.Require().NoError(s.signer.SignBlock(&future))
+	s.Require().EqualError(ErrBlockFromFuture, bc.sanityCheck(&future).Error())
+	s.Require().NoError(bc.sanityCheck(b0))
+	s.Require().NoError(bc.addBlock(b0))
+	// Non-genesis block from future.
+	b2 := s.newBlock(b0, 0, time.Minute)
+	s.Require().EqualError(ErrBlockFromFuture, bc.sanityCheck(b2).Error())
+	s.Require().NoError(bc.sanityCheck(b1))
+	// It's accepted once the local clock catches up.
+	now = now.Add(time.Minute)
+	s.Require().NoError(bc.sanityCheck(b2))
+	now = b0.Timestamp
+	s.Require().EqualError(ErrBlockFromFuture, bc.sanityCheck(b2).Error())
+	// The check is skipped when the tolerance is zero.
+	bc.configs[0].maxClockSkew = 0
+	s.Require().NoError(bc.sanityCheck(b2))
+}
+
 func (s *BlockChainTestSuite) TestNotifyRoundEvents() {
 	roundLength := uint64(10)
 	bc := s.newBlockChain(nil, roundLength)
@@ -382,6 +472,19 @@ func (s *BlockChainTestSuite) TestNotifyRoundEvents() {
 	// Make sure roundEndHeight is extended when DKG reset.
 	s.Require().Equal(bc.configs[len(bc.configs)-1].RoundEndHeight(),
 		types.GenesisHeight+roundLength*3)
+	// Heights should be mapped to rounds by appended configs.
+	for h, expected := range map[uint64]uint64{
+		types.GenesisHeight:                     0,
+		types.GenesisHeight + roundLength - 1:   0,
+		types.GenesisHeight + roundLength:       1,
+		types.GenesisHeight + roundLength*3 - 1: 1,
+	} {
+		round, found := bc.roundOfHeight(h)
+		s.Require().True(found)
+		s.Require().Equal(expected, round)
+	}
+	_, found := bc.roundOfHeight(types.GenesisHeight + roundLength*3)
+	s.Require().False(found)
 }
 
 func (s *BlockChainTestSuite) TestConfirmed() {
@@ -625,6 +728,75 @@ func (s *BlockChainTestSuite) TestPrepareBlock() {
 	prepare2(true)
 }
 
+func (s *BlockChainTestSuite) TestProposeBlockMinInterval() {
+	roundLength := uint64(2)
+	bc := s.newBlockChain(nil, roundLength)
+	// Round 1 requires a longer interval than round 0.
+	interval := 100 * s.blockInterval
+	s.Require().NoError(bc.notifyRoundEvents([]utils.RoundEventParam{
+		utils.RoundEventParam{
+			Round:       1,
+			Reset:       0,
+			BeginHeight: types.GenesisHeight + roundLength,
+			Config: &types.Config{
+				MinBlockInterval: interval,
+				RoundLength:      roundLength,
+			}}}))
+	blocks := s.newBlocks(2, nil)
+	s.Require().NoError(bc.addBlock(blocks[0]))
+	s.Require().NoError(bc.addBlock(blocks[1]))
+	b2, err := bc.proposeBlock(types.Position{
+		Round: 1, Height: types.GenesisHeight + 2}, s.dMoment, false)
+	s.Require().NoError(err)
+	s.Require().NoError(bc.sanityCheck(b2))
+	b2.Randomness = common.GenerateRandomBytes()
+	s.Require().NoError(bc.addBlock(b2))
+	// A proposer asking for a time earlier than the minimum interval should
+	// get a block respecting the interval of the tip's round.
+	b3, err := bc.proposeBlock(types.Position{
+		Round: 1, Height: types.GenesisHeight + 3}, b2.Timestamp, false)
+	s.Require().NoError(err)
+	s.Require().True(b3.Timestamp.Equal(b2.Timestamp.Add(interval)))
+	s.Require().NoError(bc.sanityCheck(b3))
+	// A later propose time is kept as it is.
+	proposeTime := b2.Timestamp.Add(2 * interval)
+	b3, err = bc.proposeBlock(types.Position{
+		Round: 1, Height: types.GenesisHeight + 3}, proposeTime, false)
+	s.Require().NoError(err)
+	s.Require().True(b3.Timestamp.Equal(proposeTime))
+}
+
+func (s *BlockChainTestSuite) TestPrepareBlockPayloadSize() {
+	app := &testPayloadApp{App: test.NewApp(0, nil, nil), payload: []byte{1, 2}}
+	bc := newBlockChain(s.nID, s.dMoment, nil, app,
+		&testTSigVerifierGetter{}, s.signer, &common.NullLogger{})
+	s.Require().NoError(bc.notifyRoundEvents([]utils.RoundEventParam{
+		utils.RoundEventParam{
+			Round:       0,
+			Reset:       0,
+			BeginHeight: types.GenesisHeight,
+			Config: &types.Config{
+				MinBlockInterval:    s.blockInterval,
+				RoundLength:         10,
+				MaxBlockPayloadSize: 1,
+			}}}))
+	b, err := bc.prepareBlock(types.Position{Height: types.GenesisHeight},
+		s.dMoment, false)
+	s.Require().Nil(b)
+	s.Require().EqualError(ErrBlockPayloadTooLarge, err.Error())
+	// Empty blocks have no payload.
+	b, err = bc.prepareBlock(types.Position{Height: types.GenesisHeight},
+		s.dMoment, true)
+	s.Require().NoError(err)
+	s.Require().NotNil(b)
+	// The check is skipped when the cap is zero.
+	bc.configs[0].maxPayloadSize = 0
+	b, err = bc.prepareBlock(types.Position{Height: types.GenesisHeight},
+		s.dMoment, false)
+	s.Require().NoError(err)
+	s.Require().NotNil(b)
+}
+
 func TestBlockChain(t *testing.T) {
 	suite.Run(t, new(BlockChainTestSuite))
 }
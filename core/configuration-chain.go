@@ -18,6 +18,7 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"sync"
@@ -25,12 +26,21 @@ import (
 
 	"github.com/dexon-foundation/dexon-consensus/common"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
 	"github.com/dexon-foundation/dexon-consensus/core/db"
 	"github.com/dexon-foundation/dexon-consensus/core/types"
 	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
 	"github.com/dexon-foundation/dexon-consensus/core/utils"
 )
 
+const (
+	// dkgStatsRetention is the count of rounds to keep DKG stats for.
+	dkgStatsRetention = 8
+	// dkgInfoRetention is the count of rounds to keep node public keys and
+	// share secrets for, including the current round.
+	dkgInfoRetention = 3
+)
+
 // Errors for configuration chain..
 var (
 	ErrDKGNotRegistered = fmt.Errorf(
@@ -43,8 +53,78 @@ var (
 		"skip but no error")
 	ErrDKGAborted = fmt.Errorf(
 		"DKG is aborted")
+	ErrInvalidDKGResult = fmt.Errorf(
+		"invalid DKG result")
 )
 
+// DKGResult is the outcome of DKG protocol of one round, it could be injected
+// to skip running DKG protocol for that round.
+type DKGResult struct {
+	GroupPublicKey *typesDKG.GroupPublicKey
+	NodePublicKeys *typesDKG.NodePublicKeys
+	// PrivateKey is the share secret of this node, it could be nil if this
+	// node is not qualified in that round.
+	PrivateKey *dkg.PrivateKey
+}
+
+// verify checks if the DKG result is consistent: the group public key should
+// be recovered from public key shares of qualified nodes, and the private key
+// should match the public key share of this node.
+func (r *DKGResult) verify(round uint64, ID types.NodeID) error {
+	gpk, npks := r.GroupPublicKey, r.NodePublicKeys
+	if gpk == nil || npks == nil || gpk.GroupPublicKey == nil {
+		return ErrInvalidDKGResult
+	}
+	if gpk.Round != round || npks.Round != round {
+		return ErrInvalidDKGResult
+	}
+	if gpk.Threshold != npks.Threshold ||
+		len(gpk.QualifyNodeIDs) != len(npks.QualifyNodeIDs) ||
+		len(npks.QualifyNodeIDs) < npks.Threshold {
+		return ErrInvalidDKGResult
+	}
+	pubShares := make([]*dkg.PublicKey, 0, len(npks.QualifyNodeIDs))
+	IDs := make(dkg.IDs, 0, len(npks.QualifyNodeIDs))
+	for nID := range npks.QualifyNodeIDs {
+		if _, exist := gpk.QualifyNodeIDs[nID]; !exist {
+			return ErrInvalidDKGResult
+		}
+		id, exist := npks.IDMap[nID]
+		if !exist || gpk.IDMap[nID] != id {
+			return ErrInvalidDKGResult
+		}
+		pubShare, exist := npks.PublicKeys[nID]
+		if !exist || pubShare == nil {
+			return ErrInvalidDKGResult
+		}
+		IDs = append(IDs, id)
+		pubShares = append(pubShares, pubShare)
+	}
+	groupPubKey, err := dkg.RecoverPublicKeyFromShares(pubShares, IDs)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(groupPubKey.Bytes(), gpk.GroupPublicKey.Bytes()) {
+		return ErrInvalidDKGResult
+	}
+	if _, qualified := npks.QualifyNodeIDs[ID]; !qualified {
+		return nil
+	}
+	if r.PrivateKey == nil {
+		return ErrInvalidDKGResult
+	}
+	// Derive the public key from the secret instead of trusting the cached
+	// one.
+	prvKey := &dkg.PrivateKey{}
+	if err := prvKey.SetBytes(r.PrivateKey.Bytes()); err != nil {
+		return ErrInvalidDKGResult
+	}
+	if !bytes.Equal(prvKey.PublicKey().Bytes(), npks.PublicKeys[ID].Bytes()) {
+		return ErrInvalidDKGResult
+	}
+	return nil
+}
+
 // ErrMismatchDKG represent an attempt to run DKG protocol is failed because
 // the register DKG protocol is mismatched, interms of round and resetCount.
 type ErrMismatchDKG struct {
@@ -70,6 +150,7 @@ type configurationChain struct {
 	dkgLock         sync.RWMutex
 	dkgSigner       map[uint64]*dkgShareSecret
 	npks            map[uint64]*typesDKG.NodePublicKeys
+	dkgLoaded       map[uint64]struct{}
 	complaints      []*typesDKG.Complaint
 	dkgResult       sync.RWMutex
 	tsig            map[common.Hash]*tsigProtocol
@@ -80,12 +161,17 @@ type configurationChain struct {
 	notarySet       map[types.NodeID]struct{}
 	mpkReady        bool
 	pendingPrvShare map[types.NodeID]*typesDKG.PrivateShare
+	// Encrypted private shares are buffered without verification, their
+	// signatures could only be verified after decryption.
+	pendingEncryptedPrvShare map[types.NodeID]*typesDKG.EncryptedPrivateShare
 	// TODO(jimmy-dexon): add timeout to pending psig.
 	pendingPsig  map[common.Hash][]*typesDKG.PartialSignature
 	prevHash     common.Hash
 	dkgCtx       context.Context
 	dkgCtxCancel context.CancelFunc
 	dkgRunning   bool
+	cipher       DKGShareCipher
+	dkgStats     map[uint64]*DKGStats
 }
 
 func newConfigurationChain(
@@ -102,12 +188,14 @@ func newConfigurationChain(
 		logger:      logger,
 		dkgSigner:   make(map[uint64]*dkgShareSecret),
 		npks:        make(map[uint64]*typesDKG.NodePublicKeys),
+		dkgLoaded:   make(map[uint64]struct{}),
 		tsig:        make(map[common.Hash]*tsigProtocol),
 		tsigTouched: make(map[common.Hash]struct{}),
 		tsigReady:   sync.NewCond(&sync.Mutex{}),
 		cache:       cache,
 		db:          dbInst,
 		pendingPsig: make(map[common.Hash][]*typesDKG.PartialSignature),
+		dkgStats:    make(map[uint64]*DKGStats),
 	}
 	configurationChain.initDKGPhasesFunc()
 	return configurationChain
@@ -168,6 +256,13 @@ func (cc *configurationChain) abortDKGNoLock(
 	return cc.dkg == nil
 }
 
+// isDKGRegistered checks if the DKG protocol of (round, reset) is registered.
+func (cc *configurationChain) isDKGRegistered(round, reset uint64) bool {
+	cc.dkgLock.RLock()
+	defer cc.dkgLock.RUnlock()
+	return cc.dkg != nil && cc.dkg.round == round && cc.dkg.reset == reset
+}
+
 func (cc *configurationChain) registerDKG(
 	parentCtx context.Context,
 	round, reset uint64,
@@ -202,6 +297,8 @@ func (cc *configurationChain) registerDKG(
 	}
 	cc.notarySet = notarySet
 	cc.pendingPrvShare = make(map[types.NodeID]*typesDKG.PrivateShare)
+	cc.pendingEncryptedPrvShare =
+		make(map[types.NodeID]*typesDKG.EncryptedPrivateShare)
 	cc.mpkReady = false
 	cc.dkg, err = recoverDKGProtocol(cc.ID, cc.recv, round, reset, cc.db)
 	cc.dkgCtx, cc.dkgCtxCancel = context.WithCancel(parentCtx)
@@ -223,6 +320,8 @@ func (cc *configurationChain) registerDKG(
 			return
 		}
 	}
+	cc.dkg.cipher = cc.cipher
+	cc.trackDKGStatsNoLock(round, reset)
 
 	go func() {
 		ticker := newTicker(cc.gov, round, TickerDKG)
@@ -236,6 +335,37 @@ func (cc *configurationChain) registerDKG(
 	}()
 }
 
+// trackDKGStatsNoLock makes the registered DKG protocol accumulate to the
+// stats of its round, stats of stale rounds are purged.
+func (cc *configurationChain) trackDKGStatsNoLock(round, reset uint64) {
+	stats, exist := cc.dkgStats[round]
+	if !exist {
+		stats = &DKGStats{Round: round}
+		cc.dkgStats[round] = stats
+	}
+	stats.Reset = reset
+	stats.Complaints += cc.dkg.stats.Complaints
+	stats.NackComplaints += cc.dkg.stats.NackComplaints
+	stats.AntiComplaints += cc.dkg.stats.AntiComplaints
+	cc.dkg.stats = stats
+	for r := range cc.dkgStats {
+		if r+dkgStatsRetention < round {
+			delete(cc.dkgStats, r)
+		}
+	}
+}
+
+// DKGStats returns complaint stats of DKG protocol of a round.
+func (cc *configurationChain) DKGStats(round uint64) (DKGStats, bool) {
+	cc.dkgLock.RLock()
+	defer cc.dkgLock.RUnlock()
+	stats, exist := cc.dkgStats[round]
+	if !exist {
+		return DKGStats{}, false
+	}
+	return *stats, true
+}
+
 func (cc *configurationChain) runDKGPhaseOne(round uint64, reset uint64) error {
 	if cc.dkg.round < round ||
 		(cc.dkg.round == round && cc.dkg.reset < reset) {
@@ -308,6 +438,14 @@ func (cc *configurationChain) runDKGPhaseTwoAndThree(
 				"error", err)
 		}
 	}
+	for _, prvShare := range cc.pendingEncryptedPrvShare {
+		if err := cc.dkg.processEncryptedPrivateShare(prvShare); err != nil {
+			cc.logger.Error("Failed to process encrypted private share",
+				"round", round,
+				"reset", reset,
+				"error", err)
+		}
+	}
 
 	// Phase 3(T = 0~λ): Propose complaint.
 	// Propose complaint is done in `processMasterPublicKeys`.
@@ -446,6 +584,14 @@ func (cc *configurationChain) runDKG(
 	if _, _, err = cc.getDKGInfo(round, false); err == nil {
 		return ErrSkipButNoError
 	}
+	if func() bool {
+		cc.dkgResult.RLock()
+		defer cc.dkgResult.RUnlock()
+		_, loaded := cc.dkgLoaded[round]
+		return loaded
+	}() {
+		return ErrSkipButNoError
+	}
 	cfg := utils.GetConfigWithPanic(cc.gov, round, cc.logger)
 	phaseHeight := uint64(
 		cfg.LambdaDKG.Nanoseconds() / cfg.MinBlockInterval.Nanoseconds())
@@ -535,6 +681,73 @@ func (cc *configurationChain) runDKG(
 	return dkgError
 }
 
+// loadDKGResult stores a verified DKG result, runDKG would skip that round.
+// The share secret is saved to DB like the one recovered by DKG protocol, a
+// result without share secret means this node is not qualified in that round.
+func (cc *configurationChain) loadDKGResult(
+	round uint64, result DKGResult) error {
+	var prvKey *dkg.PrivateKey
+	if result.PrivateKey != nil {
+		// Keep a copy, the share secret would be cleared when purged.
+		prvKey = &dkg.PrivateKey{}
+		if err := prvKey.SetBytes(result.PrivateKey.Bytes()); err != nil {
+			return err
+		}
+		if err := cc.db.PutDKGPrivateKey(
+			round, cc.gov.DKGResetCount(round), *prvKey); err != nil {
+			return err
+		}
+	}
+	cc.dkgResult.Lock()
+	defer cc.dkgResult.Unlock()
+	cc.npks[round] = result.NodePublicKeys
+	if prvKey != nil {
+		cc.dkgSigner[round] = &dkgShareSecret{privateKey: prvKey}
+	}
+	cc.dkgLoaded[round] = struct{}{}
+	return nil
+}
+
+// purgeRound releases node public keys and share secrets of rounds older than
+// dkgInfoRetention rounds before the round, share secrets are cleared before
+// released and removed from DB. Private shares kept by the DKG protocol of
+// those rounds are cleared as well.
+func (cc *configurationChain) purgeRound(round uint64) {
+	func() {
+		cc.dkgResult.Lock()
+		defer cc.dkgResult.Unlock()
+		for r, signer := range cc.dkgSigner {
+			if r+dkgInfoRetention > round {
+				continue
+			}
+			signer.privateKey.Clear()
+			delete(cc.dkgSigner, r)
+			if err := cc.db.DeleteDKGPrivateKey(r); err != nil {
+				cc.logger.Error("Failed to delete DKG private key",
+					"round", r,
+					"error", err)
+			}
+		}
+		for r := range cc.npks {
+			if r+dkgInfoRetention <= round {
+				delete(cc.npks, r)
+			}
+		}
+		for r := range cc.dkgLoaded {
+			if r+dkgInfoRetention <= round {
+				delete(cc.dkgLoaded, r)
+			}
+		}
+	}()
+	cc.dkgLock.Lock()
+	defer cc.dkgLock.Unlock()
+	if cc.dkg == nil || cc.dkg.round+dkgInfoRetention > round {
+		return
+	}
+	cc.dkg.masterPrivateShare.Clear()
+	cc.dkg.prvShares.Clear()
+}
+
 func (cc *configurationChain) isDKGFinal(round uint64) bool {
 	if !cc.gov.IsDKGFinal(round) {
 		return false
@@ -758,6 +971,12 @@ func (cc *configurationChain) processPrivateShare(
 		return ErrNotDKGParticipant
 	}
 	if !cc.mpkReady {
+		// Private shares are buffered by proposers until master public keys
+		// are ready, those not for the registered DKG protocol are ignored
+		// to avoid overwriting valid ones.
+		if prvShare.Round != cc.dkg.round || prvShare.Reset != cc.dkg.reset {
+			return nil
+		}
 		// TODO(jimmy-dexon): remove duplicated signature check in dkg module.
 		ok, err := utils.VerifyDKGPrivateShareSignature(prvShare)
 		if err != nil {
@@ -772,6 +991,28 @@ func (cc *configurationChain) processPrivateShare(
 	return cc.dkg.processPrivateShare(prvShare)
 }
 
+func (cc *configurationChain) processEncryptedPrivateShare(
+	prvShare *typesDKG.EncryptedPrivateShare) error {
+	cc.dkgLock.Lock()
+	defer cc.dkgLock.Unlock()
+	if cc.dkg == nil {
+		return nil
+	}
+	if _, exist := cc.notarySet[prvShare.ProposerID]; !exist {
+		return ErrNotDKGParticipant
+	}
+	if !cc.mpkReady {
+		if prvShare.Round != cc.dkg.round || prvShare.Reset != cc.dkg.reset {
+			return nil
+		}
+		// The signature is made on the plaintext share, it's verified by
+		// the DKG protocol after decryption.
+		cc.pendingEncryptedPrvShare[prvShare.ProposerID] = prvShare
+		return nil
+	}
+	return cc.dkg.processEncryptedPrivateShare(prvShare)
+}
+
 func (cc *configurationChain) processPartialSignature(
 	psig *typesDKG.PartialSignature) error {
 	cc.tsigReady.L.Lock()
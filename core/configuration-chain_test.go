@@ -52,6 +52,8 @@ type testCCGlobalReceiver struct {
 
 	nodes map[types.NodeID]*configurationChain
 	govs  map[types.NodeID]Governance
+	// Private shares sent to others are encrypted when cipher is not nil.
+	cipher DKGShareCipher
 }
 
 func newTestCCGlobalReceiver(
@@ -84,6 +86,25 @@ func (r *testCCGlobalReceiver) ProposeDKGPrivateShare(
 		if !exist {
 			panic(errors.New("should exist"))
 		}
+		if r.cipher != nil && prv.ReceiverID != prv.ProposerID {
+			encrypted, err := r.cipher.EncryptDKGShare(
+				nil, prv.PrivateShare.Bytes())
+			if err != nil {
+				panic(err)
+			}
+			if err := receiver.processEncryptedPrivateShare(
+				&typesDKG.EncryptedPrivateShare{
+					ProposerID:     prv.ProposerID,
+					ReceiverID:     prv.ReceiverID,
+					Round:          prv.Round,
+					Reset:          prv.Reset,
+					EncryptedShare: encrypted,
+					Signature:      prv.Signature,
+				}); err != nil {
+				panic(err)
+			}
+			return
+		}
 		if err := receiver.processPrivateShare(prv); err != nil {
 			panic(err)
 		}
@@ -533,6 +554,10 @@ func (s *ConfigurationChainTestSuite) TestDKGComplaintDelayAdd() {
 		if _, exist := cc.npks[round].QualifyNodeIDs[nID]; !exist {
 			s.FailNow("Should be qualified")
 		}
+		stats, exist := cc.DKGStats(round)
+		s.Require().True(exist)
+		s.Equal(round, stats.Round)
+		s.Equal(reset, stats.Reset)
 	}
 }
 
@@ -661,6 +686,240 @@ func (s *ConfigurationChainTestSuite) TestDKGSignerRecoverFromDB() {
 	}
 }
 
+func (s *ConfigurationChainTestSuite) TestEarlyPrivateShare() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	s.setupNodes(n)
+	recv := newTestCCGlobalReceiver(s)
+	nID := s.nIDs[0]
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true,
+	), ConfigRoundShift)
+	s.Require().NoError(err)
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	cc := newConfigurationChain(nID, newTestCCReceiver(nID, recv), gov,
+		utils.NewNodeSetCache(gov), dbInst, &common.NullLogger{})
+	recv.nodes[nID] = cc
+	recv.govs[nID] = gov
+	cc.registerDKG(context.Background(), round, reset, k)
+	newShare := func(proposer types.NodeID, round, reset uint64) (
+		prvShare *typesDKG.PrivateShare) {
+		prvShare = &typesDKG.PrivateShare{
+			ReceiverID: nID,
+			Round:      round,
+			Reset:      reset,
+		}
+		s.Require().NoError(s.signers[proposer].SignDKGPrivateShare(prvShare))
+		return
+	}
+	// Private shares arriving before master public keys are buffered.
+	s.Require().NoError(cc.processPrivateShare(newShare(s.nIDs[1], round, reset)))
+	s.Require().Len(cc.pendingPrvShare, 1)
+	// Shares not for the registered DKG protocol are ignored.
+	s.Require().NoError(
+		cc.processPrivateShare(newShare(s.nIDs[2], round+1, reset)))
+	s.Require().NoError(
+		cc.processPrivateShare(newShare(s.nIDs[2], round, reset+1)))
+	s.Require().Len(cc.pendingPrvShare, 1)
+	// A stale share should not overwrite the buffered one.
+	s.Require().NoError(
+		cc.processPrivateShare(newShare(s.nIDs[1], round-1, reset)))
+	s.Require().Equal(round, cc.pendingPrvShare[s.nIDs[1]].Round)
+}
+
+func (s *ConfigurationChainTestSuite) TestEarlyEncryptedPrivateShare() {
+	k := 2
+	n := 4
+	round := DKGDelayRound
+	reset := uint64(0)
+	s.setupNodes(n)
+	recv := newTestCCGlobalReceiver(s)
+	recv.cipher = testDKGShareCipher{}
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true,
+	), ConfigRoundShift)
+	s.Require().NoError(err)
+	for _, nID := range s.nIDs {
+		dbInst, err := db.NewMemBackedDB()
+		s.Require().NoError(err)
+		cc := newConfigurationChain(nID, newTestCCReceiver(nID, recv), gov,
+			utils.NewNodeSetCache(gov), dbInst, &common.NullLogger{})
+		cc.cipher = recv.cipher
+		recv.nodes[nID] = cc
+		recv.govs[nID] = gov
+	}
+	for _, cc := range recv.nodes {
+		cc.registerDKG(context.Background(), round, reset, k)
+	}
+	s.Require().Len(gov.DKGMasterPublicKeys(round), n)
+	proposer, receiver := recv.nodes[s.nIDs[0]], recv.nodes[s.nIDs[1]]
+	// Encrypted private shares arriving before master public keys are ready
+	// are buffered without verification.
+	func() {
+		proposer.dkgLock.Lock()
+		defer proposer.dkgLock.Unlock()
+		s.Require().NoError(proposer.runDKGPhaseTwoAndThree(round, reset))
+	}()
+	buffered := func() bool {
+		receiver.dkgLock.RLock()
+		defer receiver.dkgLock.RUnlock()
+		_, exist := receiver.pendingEncryptedPrvShare[proposer.ID]
+		return exist
+	}
+	deadline := time.Now().Add(time.Second)
+	for !buffered() {
+		s.Require().True(time.Now().Before(deadline))
+		time.Sleep(10 * time.Millisecond)
+	}
+	// They are decrypted and verified once master public keys are ready.
+	func() {
+		receiver.dkgLock.Lock()
+		defer receiver.dkgLock.Unlock()
+		s.Require().NoError(receiver.runDKGPhaseTwoAndThree(round, reset))
+		s.Require().Contains(receiver.dkg.prvSharesReceived, proposer.ID)
+	}()
+	s.Require().Empty(gov.DKGComplaints(round))
+}
+
+func (s *ConfigurationChainTestSuite) TestLoadDKGResult() {
+	k := 2
+	n := 7
+	round := DKGDelayRound
+	reset := uint64(0)
+	cfgChains := s.runDKG(k, n, round, reset)
+	hash := crypto.Keccak256Hash([]byte("Hash1"))
+	for _, cc := range cfgChains {
+		gpk, err := typesDKG.NewGroupPublicKey(round,
+			cc.gov.DKGMasterPublicKeys(round),
+			cc.gov.DKGComplaints(round), k)
+		s.Require().NoError(err)
+		result := DKGResult{
+			GroupPublicKey: gpk,
+			NodePublicKeys: cc.npks[round],
+			PrivateKey:     cc.dkgSigner[round].privateKey,
+		}
+		// The share secret is recovered by DKG protocol, its public key
+		// should be ready.
+		s.Require().Equal(cc.npks[round].PublicKeys[cc.ID].Bytes(),
+			result.PrivateKey.PublicKey().Bytes())
+		s.Require().NoError(result.verify(round, cc.ID))
+		// Mismatched round.
+		s.Require().Equal(ErrInvalidDKGResult, result.verify(round+1, cc.ID))
+		// Private key not matching the public key share.
+		for nID, other := range cfgChains {
+			if nID == cc.ID {
+				continue
+			}
+			invalid := result
+			invalid.PrivateKey = other.dkgSigner[round].privateKey
+			s.Require().Equal(ErrInvalidDKGResult, invalid.verify(round, cc.ID))
+			break
+		}
+		// Missing private key for a qualified node.
+		invalid := result
+		invalid.PrivateKey = nil
+		s.Require().Equal(ErrInvalidDKGResult, invalid.verify(round, cc.ID))
+		// The loaded result should be used to sign.
+		psig1, err := cc.preparePartialSignature(round, hash)
+		s.Require().NoError(err)
+		dbInst, err := db.NewMemBackedDB()
+		s.Require().NoError(err)
+		loadedCC := newConfigurationChain(
+			cc.ID, cc.recv, cc.gov, cc.cache, dbInst, cc.logger,
+		)
+		s.Require().NoError(loadedCC.loadDKGResult(round, result))
+		psig2, err := loadedCC.preparePartialSignature(round, hash)
+		s.Require().NoError(err)
+		s.Require().Equal(
+			psig1.PartialSignature.Signature, psig2.PartialSignature.Signature)
+		// The share secret is persisted, and DKG of that round is skipped.
+		prvKey, err := dbInst.GetDKGPrivateKey(round, reset)
+		s.Require().NoError(err)
+		s.Require().Equal(result.PrivateKey.Bytes(), prvKey.Bytes())
+		s.Require().Equal(ErrSkipButNoError,
+			loadedCC.runDKG(round, reset, nil, 0, 0))
+		// A result without share secret, like the one loaded by a node not
+		// qualified, should also skip DKG of that round.
+		dbInst, err = db.NewMemBackedDB()
+		s.Require().NoError(err)
+		unqualifiedCC := newConfigurationChain(
+			cc.ID, cc.recv, cc.gov, cc.cache, dbInst, cc.logger,
+		)
+		s.Require().NoError(unqualifiedCC.loadDKGResult(round, DKGResult{
+			GroupPublicKey: gpk,
+			NodePublicKeys: cc.npks[round],
+		}))
+		_, err = dbInst.GetDKGPrivateKey(round, reset)
+		s.Require().Equal(db.ErrDKGPrivateKeyDoesNotExist, err)
+		_, err = unqualifiedCC.preparePartialSignature(round, hash)
+		s.Require().Equal(ErrDKGNotReady, err)
+		s.Require().Equal(ErrSkipButNoError,
+			unqualifiedCC.runDKG(round, reset, nil, 0, 0))
+	}
+}
+
+func (s *ConfigurationChainTestSuite) TestPurgeRound() {
+	s.setupNodes(1)
+	nID := s.nIDs[0]
+	state := test.NewState(DKGDelayRound,
+		s.pubKeys, 100*time.Millisecond, &common.NullLogger{}, true)
+	gov, err := test.NewGovernance(state, ConfigRoundShift)
+	s.Require().NoError(err)
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	cc := newConfigurationChain(nID, newTestCCReceiver(
+		nID, newTestCCGlobalReceiver(s)), gov, utils.NewNodeSetCache(gov),
+		dbInst, &common.NullLogger{})
+	prvKeys := make(map[uint64]*dkg.PrivateKey)
+	for round := uint64(1); round <= 6; round++ {
+		prvKeys[round] = dkg.NewPrivateKey()
+		s.Require().NoError(cc.loadDKGResult(round, DKGResult{
+			NodePublicKeys: &typesDKG.NodePublicKeys{Round: round},
+			PrivateKey:     prvKeys[round],
+		}))
+	}
+	purged := cc.dkgSigner[3].privateKey
+	s.Require().Equal(prvKeys[3].Bytes(), purged.Bytes())
+	// The DKG protocol of a stale round still keeps private shares.
+	cc.dkg = newDKGProtocol(nID, cc.recv, 3, 0, 1)
+	dkgID := typesDKG.NewID(nID)
+	cc.dkg.masterPrivateShare.SetParticipants(dkg.IDs{dkgID})
+	s.Require().NoError(
+		cc.dkg.prvShares.AddShare(dkgID, dkg.NewPrivateKey()))
+	emptyKey := dkg.PrivateKey{}
+	selfShare, exists := cc.dkg.masterPrivateShare.Share(dkgID)
+	s.Require().True(exists)
+	s.Require().NotEqual(emptyKey.Bytes(), selfShare.Bytes())
+	recvShare, exists := cc.dkg.prvShares.Share(dkgID)
+	s.Require().True(exists)
+	s.Require().NotEqual(emptyKey.Bytes(), recvShare.Bytes())
+	cc.purgeRound(6)
+	s.Require().Len(cc.dkgSigner, dkgInfoRetention)
+	s.Require().Len(cc.npks, dkgInfoRetention)
+	s.Require().Len(cc.dkgLoaded, dkgInfoRetention)
+	for round := uint64(4); round <= 6; round++ {
+		s.Require().Contains(cc.dkgSigner, round)
+		s.Require().Contains(cc.npks, round)
+	}
+	// Share secrets are cleared, but not the ones passed in.
+	s.Require().NotEqual(prvKeys[3].Bytes(), purged.Bytes())
+	s.Require().Equal(prvKeys[4].Bytes(), cc.dkgSigner[4].privateKey.Bytes())
+	s.Require().Equal(emptyKey.Bytes(), selfShare.Bytes())
+	s.Require().Equal(emptyKey.Bytes(), recvShare.Bytes())
+	// Persisted share secrets of purged rounds are removed.
+	for round := uint64(1); round <= 6; round++ {
+		_, err := dbInst.GetDKGPrivateKey(round, 0)
+		if round <= 3 {
+			s.Require().Equal(db.ErrDKGPrivateKeyDoesNotExist, err)
+		} else {
+			s.Require().NoError(err)
+		}
+	}
+}
+
 func (s *ConfigurationChainTestSuite) TestDKGPhasesSnapShot() {
 	k := 2
 	n := 7
@@ -21,7 +21,9 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
@@ -55,10 +57,34 @@ var (
 		"randomness of block is incorrect")
 	ErrCannotVerifyBlockRandomness = fmt.Errorf(
 		"cannot verify block randomness")
+	ErrDKGAlreadyRunning = fmt.Errorf(
+		"dkg is already running")
+	ErrNoDKGInRound = fmt.Errorf(
+		"no dkg in round")
+	ErrBlockNotDelivered = fmt.Errorf(
+		"block not delivered")
+)
+
+// deliveredBlockChanSize is the buffer size of the channel returned by
+// Consensus.DeliveredBlockChan.
+const deliveredBlockChanSize = 1024
+
+// States of Consensus, to guard against calling Run and Stop more than once.
+const (
+	consensusStateIdle int32 = iota
+	consensusStateRunning
+	consensusStateStopped
 )
 
 type selfAgreementResult types.AgreementResult
 
+// BlockRandomness is the verified randomness of a delivered block.
+type BlockRandomness struct {
+	BlockHash  common.Hash
+	Position   types.Position
+	Randomness []byte
+}
+
 // consensusBAReceiver implements agreementReceiver.
 type consensusBAReceiver struct {
 	consensus         *Consensus
@@ -133,7 +159,7 @@ func (recv *consensusBAReceiver) VerifyPartialSignature(vote *types.Vote) (
 }
 
 func (recv *consensusBAReceiver) ProposeVote(vote *types.Vote) {
-	if !recv.isNotary {
+	if !recv.isNotary || recv.consensus.Standby() {
 		return
 	}
 	if recv.psigSigner != nil &&
@@ -172,7 +198,7 @@ func (recv *consensusBAReceiver) ProposeVote(vote *types.Vote) {
 }
 
 func (recv *consensusBAReceiver) ProposeBlock() common.Hash {
-	if !recv.isNotary {
+	if !recv.isNotary || recv.consensus.Standby() {
 		return common.Hash{}
 	}
 	block, err := recv.consensus.proposeBlock(recv.agreementModule.agreementID())
@@ -185,6 +211,9 @@ func (recv *consensusBAReceiver) ProposeBlock() common.Hash {
 			recv.consensus.logger.Error("Failed to pre-process block", "error", err)
 			return
 		}
+		if hook := recv.consensus.beforeBroadcast; hook != nil {
+			hook(block)
+		}
 		recv.consensus.logger.Debug("Calling Network.BroadcastBlock",
 			"block", block)
 		recv.consensus.network.BroadcastBlock(block)
@@ -198,6 +227,8 @@ func (recv *consensusBAReceiver) ConfirmBlock(
 		block *types.Block
 		aID   = recv.agreementModule.agreementID()
 	)
+	atomic.StoreInt64(&recv.consensus.lastConfirmedTime,
+		recv.consensus.clock().UnixNano())
 
 	isEmptyBlockConfirmed := hash == common.Hash{}
 	if isEmptyBlockConfirmed {
@@ -412,6 +443,7 @@ type consensusDKGReceiver struct {
 	nodeSetCache *utils.NodeSetCache
 	cfgModule    *configurationChain
 	network      Network
+	cipher       DKGShareCipher
 	logger       common.Logger
 }
 
@@ -458,6 +490,29 @@ func (recv *consensusDKGReceiver) ProposeDKGPrivateShare(
 			}
 		}()
 	} else {
+		if recv.cipher != nil {
+			encrypted, err := recv.cipher.EncryptDKGShare(
+				receiverPubKey, prv.PrivateShare.Bytes())
+			if err != nil {
+				recv.logger.Error("Failed to encrypt DKG private share",
+					"error", err)
+				return
+			}
+			// The signature is made on the plaintext share, the receiver
+			// would verify it after decryption.
+			recv.logger.Debug("Calling Network.SendDKGEncryptedPrivateShare",
+				"receiver", hex.EncodeToString(receiverPubKey.Bytes()))
+			recv.cipher.SendDKGEncryptedPrivateShare(receiverPubKey,
+				&typesDKG.EncryptedPrivateShare{
+					ProposerID:     prv.ProposerID,
+					ReceiverID:     prv.ReceiverID,
+					Round:          prv.Round,
+					Reset:          prv.Reset,
+					EncryptedShare: encrypted,
+					Signature:      prv.Signature,
+				})
+			return
+		}
 		recv.logger.Debug("Calling Network.SendDKGPrivateShare",
 			"receiver", hex.EncodeToString(receiverPubKey.Bytes()))
 		recv.network.SendDKGPrivateShare(receiverPubKey, prv)
@@ -513,6 +568,9 @@ type Consensus struct {
 	ID     types.NodeID
 	signer *utils.Signer
 
+	// State, accessed atomically.
+	state int32
+
 	// BA.
 	baMgr            *agreementMgr
 	baConfirmedBlock map[common.Hash]chan<- *types.Block
@@ -545,6 +603,33 @@ type Consensus struct {
 	priorityMsgChan          chan interface{}
 	waitGroup                sync.WaitGroup
 	processBlockChan         chan *types.Block
+	closeDBOnStop            bool
+	beforeBroadcast          func(*types.Block)
+	proposalBatchWindow      time.Duration
+	finalityDepth            uint64
+	batchVerifyWorkers       int
+	stallThreshold           time.Duration
+	stallChan                chan types.Position
+	standby                  int32
+	clock                    func() time.Time
+	// Time of the latest confirmation from BA in unix nano, it's accessed
+	// atomically.
+	lastConfirmedTime int64
+	// Delivered blocks not yet notified to application due to finalityDepth,
+	// it's protected by con.lock.
+	pendingFinality []*types.Block
+
+	// Channel closed when resumed, it's nil when not paused.
+	pauseLock         sync.RWMutex
+	pauseCh           chan struct{}
+	dropMsgWhenPaused bool
+
+	// Stream of delivered blocks, it's enabled by DeliveredBlockChan.
+	deliveredBlockLock    sync.Mutex
+	deliveredBlockChan    chan types.Block
+	deliveredBlockQueue   []*types.Block
+	droppedDeliveredBlock uint64
+	randomnessChan        chan BlockRandomness
 
 	// Context of Dummy receiver during switching from syncer.
 	dummyCancel    context.CancelFunc
@@ -579,6 +664,22 @@ func NewConsensusForSimulation(
 		nil, dMoment, app, gov, db, network, prv, logger, false)
 }
 
+// NewStandbyConsensus creates an instance of Consensus running as a standby
+// replica of another node with the same private key, see SetStandby.
+func NewStandbyConsensus(
+	dMoment time.Time,
+	app Application,
+	gov Governance,
+	db db.Database,
+	network Network,
+	prv crypto.PrivateKey,
+	logger common.Logger) *Consensus {
+	con := newConsensusForRound(
+		nil, dMoment, app, gov, db, network, prv, logger, true)
+	con.SetStandby()
+	return con
+}
+
 // NewConsensusFromSyncer constructs an Consensus instance from information
 // provided from syncer.
 //
@@ -677,6 +778,10 @@ func newConsensusForRound(
 	}
 	cfgModule := newConfigurationChain(ID, recv, gov, nodeSetCache, db, logger)
 	recv.cfgModule = cfgModule
+	if cipher, ok := network.(DKGShareCipher); ok {
+		recv.cipher = cipher
+		cfgModule.cipher = cipher
+	}
 	signer.SetBLSSigner(
 		func(round uint64, hash common.Hash) (crypto.Signature, error) {
 			_, signer, err := cfgModule.getDKGInfo(round, false)
@@ -714,6 +819,8 @@ func newConsensusForRound(
 		msgChan:                  make(chan types.Msg, 1024),
 		priorityMsgChan:          make(chan interface{}, 1024),
 		processBlockChan:         make(chan *types.Block, 1024),
+		stallChan:                make(chan types.Position, 16),
+		clock:                    time.Now,
 	}
 	con.ctx, con.ctxCancel = context.WithCancel(context.Background())
 	var err error
@@ -775,6 +882,12 @@ func (con *Consensus) prepare(initBlock *types.Block) (err error) {
 			con.tsigVerifierCache.Purge(e.Round + 1)
 		}
 	})
+	// Register round event handler to release DKG info of stale rounds.
+	con.roundEvent.Register(func(evts []utils.RoundEventParam) {
+		e := evts[len(evts)-1]
+		defer elapse("purge-DKG-info", e)()
+		con.cfgModule.purgeRound(e.Round)
+	})
 	// Register round event handler to abort previous running DKG if any.
 	con.roundEvent.Register(func(evts []utils.RoundEventParam) {
 		e := evts[len(evts)-1]
@@ -961,7 +1074,7 @@ func (con *Consensus) prepare(initBlock *types.Block) (err error) {
 						"reset", e.Reset)
 					return
 				}
-				nextNotarySet, err := con.nodeSetCache.GetNotarySet(nextRound)
+				isNotary, err := con.nodeSetCache.IsNotary(nextRound, con.ID)
 				if err != nil {
 					con.logger.Error("Error getting notary set for next round",
 						"round", nextRound,
@@ -969,12 +1082,18 @@ func (con *Consensus) prepare(initBlock *types.Block) (err error) {
 						"error", err)
 					return
 				}
-				if _, exist := nextNotarySet[con.ID]; !exist {
+				if !isNotary {
 					con.logger.Info("Not selected as notary set",
 						"round", nextRound,
 						"reset", e.Reset)
 					return
 				}
+				if con.Standby() {
+					con.logger.Info("Skip DKG in standby mode",
+						"round", nextRound,
+						"reset", e.Reset)
+					return
+				}
 				con.logger.Info("Selected as notary set",
 					"round", nextRound,
 					"reset", e.Reset)
@@ -1006,8 +1125,14 @@ func (con *Consensus) prepare(initBlock *types.Block) (err error) {
 	return
 }
 
-// Run starts running DEXON Consensus.
+// Run starts running DEXON Consensus. Calling it again, or after Stop, is a
+// no-op.
 func (con *Consensus) Run() {
+	if !atomic.CompareAndSwapInt32(
+		&con.state, consensusStateIdle, consensusStateRunning) {
+		con.logger.Warn("Consensus is already running or stopped")
+		return
+	}
 	// There may have emptys block in blockchain added by force sync.
 	blocksWithoutRandomness := con.bcModule.pendingBlocksWithoutRandomness()
 	// Launch BA routines.
@@ -1019,6 +1144,12 @@ func (con *Consensus) Run() {
 	con.waitGroup.Add(1)
 	go con.processMsg()
 	go con.processBlockLoop()
+	// Subscribe to governance if supported.
+	if sub, ok := con.gov.(GovernanceSubscriber); ok {
+		con.logger.Debug("Calling Governance.Subscribe")
+		con.waitGroup.Add(1)
+		go con.processGovernanceEvents(sub.Subscribe())
+	}
 	// Stop dummy receiver if launched.
 	if con.dummyCancel != nil {
 		con.logger.Trace("Stop dummy receiver")
@@ -1048,6 +1179,10 @@ func (con *Consensus) Run() {
 	time.Sleep(3 * time.Second)
 	con.waitGroup.Add(1)
 	go con.deliveryGuard()
+	if con.stallThreshold > 0 {
+		con.waitGroup.Add(1)
+		go con.stallWatchdog()
+	}
 	// Block until done.
 	select {
 	case <-con.ctx.Done():
@@ -1055,6 +1190,9 @@ func (con *Consensus) Run() {
 }
 
 func (con *Consensus) generateBlockRandomness(blocks []*types.Block) {
+	if con.Standby() {
+		return
+	}
 	con.logger.Debug("Start generating block randomness", "blocks", blocks)
 	isNotarySet := make(map[uint64]bool)
 	for _, block := range blocks {
@@ -1063,16 +1201,16 @@ func (con *Consensus) generateBlockRandomness(blocks []*types.Block) {
 		}
 		doRun, exist := isNotarySet[block.Position.Round]
 		if !exist {
-			curNotarySet, err := con.nodeSetCache.GetNotarySet(block.Position.Round)
+			isNotary, err := con.nodeSetCache.IsNotary(
+				block.Position.Round, con.ID)
 			if err != nil {
 				con.logger.Error("Error getting notary set when generate block tsig",
 					"round", block.Position.Round,
 					"error", err)
 				continue
 			}
-			_, exist := curNotarySet[con.ID]
-			isNotarySet[block.Position.Round] = exist
-			doRun = exist
+			isNotarySet[block.Position.Round] = isNotary
+			doRun = isNotary
 		}
 		if !doRun {
 			continue
@@ -1152,6 +1290,72 @@ func (con *Consensus) runDKG(
 	}()
 }
 
+// RunDKGSync runs the DKG protocol of the given round and blocks until it's
+// done. ErrNoDKGInRound is returned for rounds before DKGDelayRound.
+//
+// NOTE: it's for testing only. DKG phases are driven by a local height event
+// ticking every MinBlockInterval instead of heights of delivered blocks, thus
+// timing of phases doesn't match the one in production. All nodes in notary
+// set are expected to call it at about the same time.
+func (con *Consensus) RunDKGSync(round uint64) error {
+	if round < DKGDelayRound {
+		return ErrNoDKGInRound
+	}
+	if _, _, err := con.cfgModule.getDKGInfo(round, false); err == nil {
+		return nil
+	}
+	if !func() bool {
+		con.dkgReady.L.Lock()
+		defer con.dkgReady.L.Unlock()
+		if con.dkgRunning == 1 {
+			return false
+		}
+		con.dkgRunning = 1
+		return true
+	}() {
+		return ErrDKGAlreadyRunning
+	}
+	defer func() {
+		con.dkgReady.L.Lock()
+		defer con.dkgReady.L.Unlock()
+		con.dkgReady.Broadcast()
+		con.dkgRunning = 2
+	}()
+	reset := con.gov.DKGResetCount(round)
+	config := utils.GetConfigWithPanic(con.gov, round, con.logger)
+	if !con.cfgModule.isDKGRegistered(round, reset) {
+		con.cfgModule.registerDKG(con.ctx, round, reset,
+			utils.GetDKGThreshold(config))
+	}
+	event := common.NewEvent()
+	ctx, cancel := context.WithCancel(con.ctx)
+	defer cancel()
+	go func() {
+		height := uint64(0)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(config.MinBlockInterval):
+			}
+			event.NotifyHeight(height)
+			height++
+		}
+	}()
+	err := con.cfgModule.runDKG(round, reset, event, 0, 0)
+	if err == ErrSkipButNoError {
+		err = nil
+	}
+	return err
+}
+
+// DKGStats returns counts of complaints observed by this node in DKG protocol
+// of a round, false is returned if this node doesn't join DKG of that round or
+// those stats are purged.
+func (con *Consensus) DKGStats(round uint64) (DKGStats, bool) {
+	return con.cfgModule.DKGStats(round)
+}
+
 func (con *Consensus) runCRS(round uint64, hash common.Hash, reset bool) {
 	// Start running next round CRS.
 	psig, err := con.cfgModule.preparePartialSignature(round, hash)
@@ -1187,15 +1391,344 @@ func (con *Consensus) runCRS(round uint64, hash common.Hash, reset bool) {
 	}
 }
 
-// Stop the Consensus core.
+// CompactionChainTip returns the height and hash of the last delivered block
+// along with its witness. All values come from one consistent snapshot.
+func (con *Consensus) CompactionChainTip() (
+	height uint64, hash common.Hash, witness types.Witness) {
+	return con.bcModule.deliveredTip()
+}
+
+// NextBlock returns the height of the next block to be confirmed and the
+// earliest timestamp it could carry, which is helpful for diagnosing why the
+// chain isn't advancing. ready is false when the tip is confirmed but not yet
+// delivered, no block could be proposed until then.
+func (con *Consensus) NextBlock() (
+	height uint64, earliest time.Time, ready bool) {
+	height, earliest = con.bcModule.nextBlock()
+	if height == notReadyHeight {
+		return 0, time.Time{}, false
+	}
+	return height, earliest, true
+}
+
+// AgreementState returns a snapshot of the state of BA, which is helpful for
+// diagnosing why no block is confirmed.
+func (con *Consensus) AgreementState() AgreementState {
+	if con.baMgr.baModule == nil {
+		return AgreementState{Stopped: true}
+	}
+	return con.baMgr.baModule.snapshot()
+}
+
+// DeliveredBlockChan returns a channel streaming delivered blocks in order, it
+// is an alternative to Application.BlockDelivered which is free from calling
+// back into Consensus while it's locked. The channel is buffered; when it's
+// full, the oldest block would be dropped and counted by
+// DroppedDeliveredBlocks.
+func (con *Consensus) DeliveredBlockChan() <-chan types.Block {
+	con.deliveredBlockLock.Lock()
+	defer con.deliveredBlockLock.Unlock()
+	if con.deliveredBlockChan == nil {
+		con.deliveredBlockChan = make(chan types.Block, deliveredBlockChanSize)
+	}
+	return con.deliveredBlockChan
+}
+
+// RandomnessChan returns a channel streaming randomness of delivered blocks.
+// Randomness are sent once per block in the order of delivery, blocks in
+// rounds without randomness, i.e. before DKGDelayRound, are skipped. The
+// channel is buffered; when it's full, the oldest one would be dropped.
+func (con *Consensus) RandomnessChan() <-chan BlockRandomness {
+	con.deliveredBlockLock.Lock()
+	defer con.deliveredBlockLock.Unlock()
+	if con.randomnessChan == nil {
+		con.randomnessChan = make(chan BlockRandomness, deliveredBlockChanSize)
+	}
+	return con.randomnessChan
+}
+
+// DroppedDeliveredBlocks returns the count of delivered blocks dropped from
+// the channel returned by DeliveredBlockChan.
+func (con *Consensus) DroppedDeliveredBlocks() uint64 {
+	con.deliveredBlockLock.Lock()
+	defer con.deliveredBlockLock.Unlock()
+	return con.droppedDeliveredBlock
+}
+
+// ValidateBlock runs the same position, timestamp, hash and signature checks
+// applied to blocks proposed in BA, without changing any state. It reads the
+// tip of the chain under the read lock of blockChain. ErrRetrySanityCheckLater
+// means the block is ahead of the tip and should be checked again later.
+func (con *Consensus) ValidateBlock(b *types.Block) error {
+	return con.bcModule.sanityCheck(b)
+}
+
+// GetBlockByPosition returns the delivered block at a position.
+// ErrBlockNotDelivered is returned if no block is delivered at that height
+// yet.
+func (con *Consensus) GetBlockByPosition(pos types.Position) (
+	*types.Block, error) {
+	b, err := con.deliveredBlockByHeight(pos.Height)
+	if err != nil {
+		return nil, err
+	}
+	if b.Position.Round != pos.Round {
+		return nil, db.ErrBlockDoesNotExist
+	}
+	return b, nil
+}
+
+// RoundForHeight returns the round a block height belongs to. Heights not yet
+// delivered are mapped by round configurations notified so far,
+// ErrConfigurationNotReady is returned if the round of that height is not
+// decided yet.
+func (con *Consensus) RoundForHeight(height uint64) (uint64, error) {
+	if round, found := con.bcModule.roundOfHeight(height); found {
+		return round, nil
+	}
+	b, err := con.deliveredBlockByHeight(height)
+	if err == ErrBlockNotDelivered {
+		return 0, ErrConfigurationNotReady
+	}
+	if err != nil {
+		return 0, err
+	}
+	return b.Position.Round, nil
+}
+
+// RoundForBlock returns the round a block belongs to by its height.
+func (con *Consensus) RoundForBlock(b *types.Block) (uint64, error) {
+	return con.RoundForHeight(b.Position.Height)
+}
+
+// deliveredBlockByHeight returns the delivered block at a height.
+func (con *Consensus) deliveredBlockByHeight(height uint64) (
+	*types.Block, error) {
+	hash, err := con.db.GetCompactionChainBlockHash(height)
+	if err == db.ErrBlockDoesNotExist {
+		return nil, ErrBlockNotDelivered
+	}
+	if err != nil {
+		return nil, err
+	}
+	b, err := con.db.GetBlock(hash)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// NotarySet returns a snapshot of the notary set of the round of the next
+// block. The DKG set of a round is its notary set.
+func (con *Consensus) NotarySet() (map[types.NodeID]struct{}, error) {
+	return con.nodeSetCache.GetNotarySet(con.bcModule.tipRound())
+}
+
+// DKGDisqualified returns the nodes disqualified in DKG of one round, along
+// with the reasons why they are disqualified.
+func (con *Consensus) DKGDisqualified(round uint64) map[types.NodeID]string {
+	config := con.gov.Configuration(round)
+	if config == nil {
+		return nil
+	}
+	return typesDKG.CalcDisqualifyNodes(
+		con.gov.DKGComplaints(round), utils.GetDKGThreshold(config))
+}
+
+// LoadDKGResult injects a pre-computed DKG result of one round, the DKG
+// protocol of that round would be skipped. The result is verified before
+// accepted. Note that governance is still consulted for DKG set of that round.
+func (con *Consensus) LoadDKGResult(round uint64, result DKGResult) error {
+	if err := result.verify(round, con.ID); err != nil {
+		return err
+	}
+	if err := con.tsigVerifierCache.Add(
+		round, result.GroupPublicKey); err != nil {
+		return err
+	}
+	if err := con.cfgModule.loadDKGResult(round, result); err != nil {
+		return err
+	}
+	con.logger.Info("DKG result loaded", "round", round)
+	return nil
+}
+
+// BARoundLatencies returns the distribution of durations from BA restarting
+// at a position to a block being confirmed at that position.
+func (con *Consensus) BARoundLatencies() LatencyHistogram {
+	return con.baMgr.baLatencies()
+}
+
+// Pause stops BA and message processing without tearing down any state.
+// Messages received when paused would be dropped if dropMsg is true,
+// otherwise they're buffered until channels are full. Missing blocks and
+// votes would be pulled after resumed.
+func (con *Consensus) Pause(dropMsg bool) {
+	con.pauseLock.Lock()
+	defer con.pauseLock.Unlock()
+	if con.pauseCh != nil {
+		return
+	}
+	con.pauseCh = make(chan struct{})
+	con.dropMsgWhenPaused = dropMsg
+	con.logger.Info("Consensus paused", "drop-msg", dropMsg)
+}
+
+// Resume BA and message processing paused by Pause.
+func (con *Consensus) Resume() {
+	con.pauseLock.Lock()
+	defer con.pauseLock.Unlock()
+	if con.pauseCh == nil {
+		return
+	}
+	close(con.pauseCh)
+	con.pauseCh = nil
+	con.logger.Info("Consensus resumed")
+}
+
+// Paused checks if Consensus is paused.
+func (con *Consensus) Paused() bool {
+	con.pauseLock.RLock()
+	defer con.pauseLock.RUnlock()
+	return con.pauseCh != nil
+}
+
+// waitResumed blocks until Consensus is not paused, false is returned when
+// Consensus is stopped.
+func (con *Consensus) waitResumed() bool {
+	ch := func() chan struct{} {
+		con.pauseLock.RLock()
+		defer con.pauseLock.RUnlock()
+		return con.pauseCh
+	}()
+	if ch == nil {
+		return true
+	}
+	select {
+	case <-ch:
+		return true
+	case <-con.ctx.Done():
+		return false
+	}
+}
+
+func (con *Consensus) shouldDropMsg() bool {
+	con.pauseLock.RLock()
+	defer con.pauseLock.RUnlock()
+	return con.pauseCh != nil && con.dropMsgWhenPaused
+}
+
+// CloseDBOnStop makes Stop close the database once all routines are stopped,
+// that is, the database is owned by Consensus. By default, closing the
+// database is left to callers. It should be called before Stop.
+func (con *Consensus) CloseDBOnStop() {
+	con.closeDBOnStop = true
+}
+
+// SetBeforeBroadcastHook sets a hook called synchronously with each block
+// proposed by this node, right before it's broadcasted. The block passed to
+// the hook is exactly the one to be broadcasted, and should not be modified.
+// It should be called before Run.
+func (con *Consensus) SetBeforeBroadcastHook(hook func(*types.Block)) {
+	con.beforeBroadcast = hook
+}
+
+// SetProposalBatchWindow makes this node wait for the window after
+// MinBlockInterval before starting BA of the next position, thus payloads
+// could be accumulated into larger blocks. The wait is done outside of BA,
+// votes are still processed meanwhile. The window is capped at half of
+// LambdaBA. The default zero window means proposing immediately. It should
+// be called before Run.
+func (con *Consensus) SetProposalBatchWindow(window time.Duration) {
+	con.proposalBatchWindow = window
+}
+
+// SetTSigVerifierStore makes group public keys derived for verifying
+// threshold signatures persisted to the store, thus they don't have to be
+// derived again after restarting. It should be called before Run.
+func (con *Consensus) SetTSigVerifierStore(store TSigVerifierStore) {
+	con.tsigVerifierCache.SetStore(store)
+}
+
+// SetStandby makes this node a standby replica of another node with the same
+// private key. A standby replica processes messages and delivers blocks like
+// others, but doesn't propose blocks, votes, partial signatures and doesn't
+// join DKG. It should be called before Run, or use NewStandbyConsensus
+// instead.
+func (con *Consensus) SetStandby() {
+	atomic.StoreInt32(&con.standby, 1)
+}
+
+// Promote makes a standby replica start proposing, it takes effect from the
+// next proposal. DKG results of rounds not joined in standby mode should be
+// provided via LoadDKGResult.
+func (con *Consensus) Promote() {
+	if atomic.SwapInt32(&con.standby, 0) == 1 {
+		con.logger.Info("Promoted from standby mode", "ID", con.ID)
+	}
+}
+
+// Standby checks if this node is a standby replica.
+func (con *Consensus) Standby() bool {
+	return atomic.LoadInt32(&con.standby) == 1
+}
+
+// SetFinalityDepth delays Application.BlockDelivered of a delivered block until
+// depth more blocks are delivered after it. Blocks still waiting are notified
+// in order when stopped. The compaction chain tip in DB, RoundForHeight and
+// channels returned by DeliveredBlockChan and RandomnessChan only reflect
+// notified blocks. The default zero depth means notifying immediately. It
+// should be called before Run.
+func (con *Consensus) SetFinalityDepth(depth uint64) {
+	con.finalityDepth = depth
+}
+
+// SetBatchVerifyWorkers makes ProcessBlockBatch verify signatures of blocks
+// by the number of workers before processing them. The default zero means
+// blocks in batches are trusted and not verified. It should be called before
+// Run.
+func (con *Consensus) SetBatchVerifyWorkers(workers int) {
+	con.batchVerifyWorkers = workers
+}
+
+// SetStallThreshold makes this node report BA stalls via StallChan when no block
+// is confirmed by BA for the threshold. The default zero threshold disables the
+// report. It should be called before Run.
+func (con *Consensus) SetStallThreshold(threshold time.Duration) {
+	con.stallThreshold = threshold
+}
+
+// StallChan returns a channel for positions of stalled BA, see
+// SetStallThreshold. Stalls are dropped when the channel is full.
+func (con *Consensus) StallChan() <-chan types.Position {
+	return con.stallChan
+}
+
+// Stop the Consensus core. Once stopped, Consensus could not be run again.
 func (con *Consensus) Stop() {
+	if atomic.SwapInt32(
+		&con.state, consensusStateStopped) == consensusStateStopped {
+		con.logger.Warn("Consensus is already stopped")
+		return
+	}
 	con.ctxCancel()
 	con.baMgr.stop()
 	con.event.Reset()
 	con.waitGroup.Wait()
+	func() {
+		con.lock.Lock()
+		defer con.lock.Unlock()
+		con.notifyFinalBlocks(0)
+	}()
+	con.flushDeliveredBlocks()
 	if nbApp, ok := con.app.(*nonBlocking); ok {
 		nbApp.wait()
 	}
+	if con.closeDBOnStop {
+		if err := con.db.Close(); err != nil {
+			con.logger.Error("Unable to close database", "error", err)
+		}
+	}
 }
 
 func (con *Consensus) deliverNetworkMsg() {
@@ -1209,6 +1742,9 @@ func (con *Consensus) deliverNetworkMsg() {
 		}
 		select {
 		case msg := <-recv:
+			if con.shouldDropMsg() {
+				continue
+			}
 		innerLoop:
 			for {
 				select {
@@ -1225,6 +1761,38 @@ func (con *Consensus) deliverNetworkMsg() {
 	}
 }
 
+func (con *Consensus) processGovernanceEvents(ch <-chan GovernanceEvent) {
+	defer con.waitGroup.Done()
+	for {
+		select {
+		case <-con.ctx.Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			con.processGovernanceEvent(e)
+		}
+	}
+}
+
+// processGovernanceEvent invalidates caches affected by an update of
+// governance data.
+func (con *Consensus) processGovernanceEvent(e GovernanceEvent) {
+	con.logger.Debug("Governance updated", "type", e.Type, "round", e.Round)
+	switch e.Type {
+	case GovernanceEventConfig:
+		con.nodeSetCache.Purge(e.Round)
+	case GovernanceEventCRS:
+		// Notary set is derived from CRS, and a new CRS means the DKG of that
+		// round would be run again.
+		con.nodeSetCache.Purge(e.Round)
+		con.tsigVerifierCache.Purge(e.Round)
+	default:
+		con.logger.Warn("Unknown governance event", "event", e)
+	}
+}
+
 func (con *Consensus) processMsg() {
 	defer con.waitGroup.Done()
 MessageLoop:
@@ -1234,6 +1802,9 @@ MessageLoop:
 			return
 		default:
 		}
+		if !con.waitResumed() {
+			return
+		}
 		var msg, peer interface{}
 		select {
 		case msg = <-con.priorityMsgChan:
@@ -1356,6 +1927,13 @@ MessageLoop:
 					"error", err)
 				con.network.ReportBadPeerChan() <- peer
 			}
+		case *typesDKG.EncryptedPrivateShare:
+			if err := con.cfgModule.processEncryptedPrivateShare(
+				val); err != nil {
+				con.logger.Error("Failed to process encrypted private share",
+					"error", err)
+				con.network.ReportBadPeerChan() <- peer
+			}
 
 		case *typesDKG.PartialSignature:
 			if err := con.cfgModule.processPartialSignature(val); err != nil {
@@ -1470,6 +2048,42 @@ func (con *Consensus) deliveryGuard() {
 	}
 }
 
+func (con *Consensus) stallWatchdog() {
+	defer con.waitGroup.Done()
+	atomic.StoreInt64(&con.lastConfirmedTime, con.clock().UnixNano())
+	for {
+		select {
+		case <-con.ctx.Done():
+			return
+		case <-time.After(con.stallThreshold / 4):
+		}
+		con.checkStall()
+	}
+}
+
+// checkStall reports a BA stall if no block is confirmed for stallThreshold,
+// a stall is reported once per stallThreshold.
+func (con *Consensus) checkStall() {
+	now := con.clock()
+	last := atomic.LoadInt64(&con.lastConfirmedTime)
+	if now.Sub(time.Unix(0, last)) < con.stallThreshold {
+		return
+	}
+	if !atomic.CompareAndSwapInt64(
+		&con.lastConfirmedTime, last, now.UnixNano()) {
+		// A block is just confirmed.
+		return
+	}
+	pos := con.baMgr.baModule.agreementID()
+	con.logger.Warn("No block confirmed by BA for too long",
+		"position", pos,
+		"since", time.Unix(0, last).UTC())
+	select {
+	case con.stallChan <- pos:
+	default:
+	}
+}
+
 // deliverBlock deliver a block to application layer.
 func (con *Consensus) deliverBlock(b *types.Block) {
 	select {
@@ -1479,20 +2093,94 @@ func (con *Consensus) deliverBlock(b *types.Block) {
 	if err := con.db.PutBlock(*b); err != nil {
 		panic(err)
 	}
-	if err := con.db.PutCompactionChainTipInfo(b.Hash,
-		b.Position.Height); err != nil {
-		panic(err)
+	con.pendingFinality = append(con.pendingFinality, b)
+	con.notifyFinalBlocks(con.finalityDepth)
+}
+
+// notifyFinalBlocks notifies application of delivered blocks in order until
+// only depth blocks are left waiting. The compaction chain tip, which indexes
+// blocks by height in DB, and channels returned by DeliveredBlockChan and
+// RandomnessChan are updated along with the notification, so a pending block
+// is invisible outside until the application sees it, and would be delivered
+// again after restarting from the DB. It should be called with con.lock held.
+func (con *Consensus) notifyFinalBlocks(depth uint64) {
+	for uint64(len(con.pendingFinality)) > depth {
+		b := con.pendingFinality[0]
+		con.pendingFinality = con.pendingFinality[1:]
+		if err := con.db.PutCompactionChainTipInfo(b.Hash,
+			b.Position.Height); err != nil {
+			panic(err)
+		}
+		con.logger.Debug("Calling Application.BlockDelivered", "block", b)
+		con.app.BlockDelivered(b.Hash, b.Position,
+			common.CopyBytes(b.Randomness))
+		if con.debugApp != nil {
+			con.debugApp.BlockReady(b.Hash)
+		}
+		con.queueDeliveredBlock(b)
 	}
-	con.logger.Debug("Calling Application.BlockDelivered", "block", b)
-	con.app.BlockDelivered(b.Hash, b.Position, common.CopyBytes(b.Randomness))
-	if con.debugApp != nil {
-		con.debugApp.BlockReady(b.Hash)
+}
+
+// queueDeliveredBlock queues a delivered block to be sent to channels
+// returned by DeliveredBlockChan and RandomnessChan, the queue is flushed by
+// flushDeliveredBlocks after con.lock is released.
+func (con *Consensus) queueDeliveredBlock(b *types.Block) {
+	con.deliveredBlockLock.Lock()
+	defer con.deliveredBlockLock.Unlock()
+	if con.deliveredBlockChan == nil && con.randomnessChan == nil {
+		return
+	}
+	con.deliveredBlockQueue = append(con.deliveredBlockQueue, b.Clone())
+}
+
+// flushDeliveredBlocks sends queued delivered blocks to channels returned by
+// DeliveredBlockChan and RandomnessChan without blocking. The oldest one in
+// the channel would be dropped when the channel is full.
+func (con *Consensus) flushDeliveredBlocks() {
+	con.deliveredBlockLock.Lock()
+	defer con.deliveredBlockLock.Unlock()
+	for _, b := range con.deliveredBlockQueue {
+		if con.randomnessChan != nil && b.Position.Round >= DKGDelayRound {
+			r := BlockRandomness{
+				BlockHash:  b.Hash,
+				Position:   b.Position,
+				Randomness: common.CopyBytes(b.Randomness),
+			}
+			for sent := false; !sent; {
+				select {
+				case con.randomnessChan <- r:
+					sent = true
+				default:
+					select {
+					case <-con.randomnessChan:
+					default:
+					}
+				}
+			}
+		}
+		if con.deliveredBlockChan == nil {
+			continue
+		}
+		for sent := false; !sent; {
+			select {
+			case con.deliveredBlockChan <- *b:
+				sent = true
+			default:
+				select {
+				case <-con.deliveredBlockChan:
+					con.droppedDeliveredBlock++
+				default:
+				}
+			}
+		}
 	}
+	con.deliveredBlockQueue = nil
 }
 
 // deliverFinalizedBlocks extracts and delivers finalized blocks to application
 // layer.
 func (con *Consensus) deliverFinalizedBlocks() error {
+	defer con.flushDeliveredBlocks()
 	con.lock.Lock()
 	defer con.lock.Unlock()
 	return con.deliverFinalizedBlocksWithoutLock()
@@ -1535,6 +2223,7 @@ func (con *Consensus) processBlock(block *types.Block) (err error) {
 	// Block processed by blockChain can be out-of-order. But the output from
 	// blockChain (deliveredBlocks) cannot, thus we need to protect the part
 	// below with writer lock.
+	defer con.flushDeliveredBlocks()
 	con.lock.Lock()
 	defer con.lock.Unlock()
 	if err = con.bcModule.addBlock(block); err != nil {
@@ -1546,6 +2235,44 @@ func (con *Consensus) processBlock(block *types.Block) (err error) {
 	return
 }
 
+// ProcessBlockBatch submits a batch of blocks to a Consensus instance under
+// one lock acquisition, which is useful when catching up historical blocks.
+// Blocks are processed in the order of their positions, those already
+// confirmed are skipped. The delivered blocks would be identical to
+// submitting them one by one. When SetBatchVerifyWorkers is called, the
+// whole batch is rejected if any block fails the verification.
+func (con *Consensus) ProcessBlockBatch(blocks []*types.Block) (err error) {
+	sorted := make(types.BlocksByPosition, len(blocks))
+	copy(sorted, blocks)
+	sort.Sort(sorted)
+	// Verify signatures concurrently before acquiring the lock.
+	if con.batchVerifyWorkers > 0 {
+		for _, vErr := range utils.VerifyBlocks(
+			sorted, con.batchVerifyWorkers) {
+			if vErr != nil {
+				return vErr
+			}
+		}
+	}
+	defer con.flushDeliveredBlocks()
+	con.lock.Lock()
+	defer con.lock.Unlock()
+	defer func() {
+		if dErr := con.deliverFinalizedBlocksWithoutLock(); err == nil {
+			err = dErr
+		}
+	}()
+	for _, b := range sorted {
+		if con.bcModule.confirmed(b.Position.Height) {
+			continue
+		}
+		if err = con.bcModule.addBlock(b); err != nil {
+			return
+		}
+	}
+	return
+}
+
 // PrepareBlock would setup header fields of block based on its ProposerID.
 func (con *Consensus) proposeBlock(position types.Position) (
 	*types.Block, error) {
@@ -20,7 +20,10 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -75,6 +78,71 @@ func (n *network) SendDKGPrivateShare(
 	n.conn.send(n.nID, types.NewNodeID(recv), prvShare)
 }
 
+// cipherNetwork implements core.Network and DKGShareCipher.
+type cipherNetwork struct {
+	*network
+	testDKGShareCipher
+}
+
+// SendDKGPrivateShare should not be called when private shares are encrypted.
+func (n *cipherNetwork) SendDKGPrivateShare(
+	crypto.PublicKey, *typesDKG.PrivateShare) {
+	panic(errors.New("private share is not encrypted"))
+}
+
+// SendDKGEncryptedPrivateShare sends EncryptedPrivateShare to a DKG
+// participant.
+func (n *cipherNetwork) SendDKGEncryptedPrivateShare(
+	recv crypto.PublicKey, prvShare *typesDKG.EncryptedPrivateShare) {
+	n.conn.send(n.nID, types.NewNodeID(recv), prvShare)
+}
+
+// primaryNetwork implements core.Network, messages broadcast by it are also
+// sent to its standby replica.
+type primaryNetwork struct {
+	*network
+	replica chan<- types.Msg
+}
+
+func (n *primaryNetwork) mirror(msg interface{}) {
+	n.replica <- types.Msg{
+		PeerID:  n.nID,
+		Payload: msg,
+	}
+}
+
+// BroadcastVote broadcasts vote to all nodes and the standby replica.
+func (n *primaryNetwork) BroadcastVote(vote *types.Vote) {
+	n.network.BroadcastVote(vote)
+	n.mirror(vote)
+}
+
+// BroadcastBlock broadcasts block to all nodes and the standby replica.
+func (n *primaryNetwork) BroadcastBlock(block *types.Block) {
+	n.network.BroadcastBlock(block)
+	n.mirror(block.Clone())
+}
+
+// BroadcastAgreementResult broadcasts agreement result to DKG set and the
+// standby replica.
+func (n *primaryNetwork) BroadcastAgreementResult(
+	result *types.AgreementResult) {
+	n.network.BroadcastAgreementResult(result)
+	n.mirror(result)
+}
+
+// standbyNetwork implements core.Network, it receives messages broadcast by
+// the primary node.
+type standbyNetwork struct {
+	*network
+	recv <-chan types.Msg
+}
+
+// ReceiveChan returns a channel to receive messages from the primary node.
+func (n *standbyNetwork) ReceiveChan() <-chan types.Msg {
+	return n.recv
+}
+
 // BroadcastDKGPrivateShare broadcasts PrivateShare to all DKG participants.
 func (n *network) BroadcastDKGPrivateShare(
 	prvShare *typesDKG.PrivateShare) {
@@ -171,6 +239,8 @@ func (nc *networkConnection) setCon(nID types.NodeID, con *Consensus) {
 				err = con.ProcessAgreementResult(val)
 			case *typesDKG.PrivateShare:
 				err = con.cfgModule.processPrivateShare(val)
+			case *typesDKG.EncryptedPrivateShare:
+				err = con.cfgModule.processEncryptedPrivateShare(val)
 			case *typesDKG.PartialSignature:
 				err = con.cfgModule.processPartialSignature(val)
 			}
@@ -234,6 +304,13 @@ func (s *ConsensusTestSuite) prepareConsensus(
 	return app, con
 }
 
+// waitDelivered waits until blocks delivered by con reach the application.
+func (s *ConsensusTestSuite) waitDelivered(con *Consensus) {
+	if nbApp, ok := con.app.(*nonBlocking); ok {
+		nbApp.wait()
+	}
+}
+
 func (s *ConsensusTestSuite) prepareConsensusWithDB(
 	dMoment time.Time,
 	gov *test.Governance,
@@ -342,6 +419,95 @@ func (s *ConsensusTestSuite) TestDKGCRS() {
 	s.NotNil(gov.CRS(1))
 }
 
+func (s *ConsensusTestSuite) TestRunDKGSync() {
+	n := 4
+	lambda := 100 * time.Millisecond
+	if isTravisCI() {
+		lambda *= 5
+	}
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(n)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, lambda, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	cons := map[types.NodeID]*Consensus{}
+	dMoment := time.Now().UTC()
+	for _, key := range prvKeys {
+		_, con := s.prepareConsensus(dMoment, gov, key, conn)
+		cons[types.NewNodeID(key.PublicKey())] = con
+	}
+	time.Sleep(gov.Configuration(0).MinBlockInterval * 4)
+	// There is no DKG before DKGDelayRound.
+	for _, con := range cons {
+		s.Require().Equal(ErrNoDKGInRound, con.RunDKGSync(0))
+	}
+	errs := make(chan error, len(cons))
+	for _, con := range cons {
+		go func(con *Consensus) {
+			errs <- con.RunDKGSync(DKGDelayRound)
+		}(con)
+	}
+	for range cons {
+		s.Require().NoError(<-errs)
+	}
+	// DKG result is available right after RunDKGSync returns.
+	for _, con := range cons {
+		npks, signer, err := con.cfgModule.getDKGInfo(DKGDelayRound, false)
+		s.Require().NoError(err)
+		s.Require().NotNil(npks)
+		s.Require().NotNil(signer)
+	}
+	// Running DKG for a ready round is a no-op.
+	for _, con := range cons {
+		s.Require().NoError(con.RunDKGSync(DKGDelayRound))
+	}
+}
+
+func (s *ConsensusTestSuite) TestEncryptedDKGPrivateShare() {
+	n := 4
+	lambda := 100 * time.Millisecond
+	if isTravisCI() {
+		lambda *= 5
+	}
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(n)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, lambda, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	cons := map[types.NodeID]*Consensus{}
+	dMoment := time.Now().UTC()
+	for _, key := range prvKeys {
+		dbInst, err := db.NewMemBackedDB()
+		s.Require().NoError(err)
+		nID := types.NewNodeID(key.PublicKey())
+		con := NewConsensus(dMoment, test.NewApp(0, nil, nil), gov, dbInst,
+			&cipherNetwork{network: conn.newNetwork(nID)}, key,
+			&common.NullLogger{})
+		conn.setCon(nID, con)
+		cons[nID] = con
+	}
+	time.Sleep(gov.Configuration(0).MinBlockInterval * 4)
+	// Private shares are sent encrypted, and DKG succeeds with them.
+	errs := make(chan error, len(cons))
+	for _, con := range cons {
+		go func(con *Consensus) {
+			errs <- con.RunDKGSync(DKGDelayRound)
+		}(con)
+	}
+	for range cons {
+		s.Require().NoError(<-errs)
+	}
+	for _, con := range cons {
+		npks, signer, err := con.cfgModule.getDKGInfo(DKGDelayRound, false)
+		s.Require().NoError(err)
+		s.Require().Len(npks.QualifyNodeIDs, n)
+		s.Require().NotNil(signer)
+	}
+	s.Require().Empty(gov.DKGComplaints(DKGDelayRound))
+}
+
 func (s *ConsensusTestSuite) TestSyncBA() {
 	lambdaBA := time.Second
 	conn := s.newNetworkConnection()
@@ -433,6 +599,664 @@ func (s *ConsensusTestSuite) TestInitialHeightEventTriggered() {
 	s.Require().Equal(con.bcModule.configs[0].RoundEndHeight(), uint64(301))
 }
 
+func (s *ConsensusTestSuite) TestDeliveredBlockChan() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(1)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	_, con := s.prepareConsensus(time.Now().UTC(), gov, prvKeys[0], conn)
+	newBlock := func(height uint64) *types.Block {
+		return &types.Block{
+			Hash:     common.NewRandomHash(),
+			Position: types.Position{Height: height},
+		}
+	}
+	// Nothing is queued before the channel is requested.
+	con.queueDeliveredBlock(newBlock(0))
+	s.Require().Len(con.deliveredBlockQueue, 0)
+	ch := con.DeliveredBlockChan()
+	s.Require().True(ch == con.DeliveredBlockChan())
+	// Overflow the channel, the oldest ones should be dropped.
+	for i := uint64(1); i <= deliveredBlockChanSize+2; i++ {
+		con.queueDeliveredBlock(newBlock(i))
+	}
+	con.flushDeliveredBlocks()
+	s.Require().Len(con.deliveredBlockQueue, 0)
+	s.Require().Equal(uint64(2), con.DroppedDeliveredBlocks())
+	s.Require().Len(ch, deliveredBlockChanSize)
+	for i := uint64(3); i <= deliveredBlockChanSize+2; i++ {
+		b := <-ch
+		s.Require().Equal(i, b.Position.Height)
+	}
+}
+
+func (s *ConsensusTestSuite) TestRandomnessChan() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(1)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	_, con := s.prepareConsensus(time.Now().UTC(), gov, prvKeys[0], conn)
+	ch := con.RandomnessChan()
+	s.Require().True(ch == con.RandomnessChan())
+	// Blocks without randomness are skipped.
+	con.queueDeliveredBlock(&types.Block{
+		Hash:     common.NewRandomHash(),
+		Position: types.Position{Round: DKGDelayRound - 1, Height: 1},
+	})
+	b := &types.Block{
+		Hash:       common.NewRandomHash(),
+		Position:   types.Position{Round: DKGDelayRound, Height: 2},
+		Randomness: []byte{1, 2, 3},
+	}
+	con.queueDeliveredBlock(b)
+	con.flushDeliveredBlocks()
+	s.Require().Len(ch, 1)
+	r := <-ch
+	s.Require().Equal(b.Hash, r.BlockHash)
+	s.Require().Equal(b.Position, r.Position)
+	s.Require().Equal(b.Randomness, r.Randomness)
+	// The delivered block channel is not affected.
+	s.Require().Nil(con.deliveredBlockChan)
+}
+
+func (s *ConsensusTestSuite) TestRunStopGuard() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(1)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	// Stop before Run, Run should not launch anything.
+	_, con := s.prepareConsensus(time.Now().UTC(), gov, prvKeys[0], conn)
+	con.Stop()
+	con.Stop()
+	con.Run()
+	s.Require().Equal(consensusStateStopped, atomic.LoadInt32(&con.state))
+	// Run twice, the second call should return immediately.
+	_, con = s.prepareConsensus(time.Now().UTC(), gov, prvKeys[0], conn)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		con.Run()
+	}()
+	for atomic.LoadInt32(&con.state) != consensusStateRunning {
+		time.Sleep(10 * time.Millisecond)
+	}
+	con.Run()
+	con.Stop()
+	con.Stop()
+	select {
+	case <-finished:
+	case <-time.After(10 * time.Second):
+		s.FailNow("Run is not returned after Stop")
+	}
+}
+
+func (s *ConsensusTestSuite) TestNotarySet() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(4)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	_, con := s.prepareConsensus(time.Now().UTC(), gov, prvKeys[0], conn)
+	notarySet, err := con.NotarySet()
+	s.Require().NoError(err)
+	s.Require().Len(notarySet, len(pubKeys))
+	for _, key := range pubKeys {
+		s.Require().Contains(notarySet, types.NewNodeID(key))
+	}
+	// The returned set is a copy.
+	for nID := range notarySet {
+		delete(notarySet, nID)
+	}
+	notarySet, err = con.NotarySet()
+	s.Require().NoError(err)
+	s.Require().Len(notarySet, len(pubKeys))
+}
+
+func (s *ConsensusTestSuite) TestLatencyHistogram() {
+	h := newLatencyHistogram([]time.Duration{time.Second, 2 * time.Second})
+	h.add(500 * time.Millisecond)
+	h.add(time.Second)
+	h.add(1500 * time.Millisecond)
+	h.add(time.Minute)
+	s.Require().Equal([]uint64{2, 1, 1}, h.Counts)
+	s.Require().Equal(uint64(4), h.Total)
+	s.Require().Equal(time.Minute+3*time.Second, h.Sum)
+	// Cloned histogram is not affected by the original one.
+	copied := h.Clone()
+	h.add(time.Minute)
+	s.Require().Equal([]uint64{2, 1, 1}, copied.Counts)
+	s.Require().Equal(uint64(4), copied.Total)
+}
+
+func (s *ConsensusTestSuite) TestPauseResume() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(1)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	_, con := s.prepareConsensus(time.Now().UTC(), gov, prvKeys[0], conn)
+	s.Require().True(con.waitResumed())
+	s.Require().False(con.shouldDropMsg())
+	// Messages are buffered when paused.
+	con.Pause(false)
+	s.Require().True(con.Paused())
+	s.Require().False(con.shouldDropMsg())
+	resumed := make(chan bool, 1)
+	go func() { resumed <- con.waitResumed() }()
+	select {
+	case <-resumed:
+		s.FailNow("Should wait until resumed")
+	case <-time.After(100 * time.Millisecond):
+	}
+	con.Resume()
+	s.Require().False(con.Paused())
+	s.Require().True(<-resumed)
+	// Messages are dropped when paused.
+	con.Pause(true)
+	s.Require().True(con.shouldDropMsg())
+	// Waiting routines should quit when stopped.
+	go func() { resumed <- con.waitResumed() }()
+	con.Stop()
+	s.Require().False(<-resumed)
+}
+
+type testCloseCountDB struct {
+	db.Database
+	closed   int
+	closeErr error
+}
+
+func (d *testCloseCountDB) Close() error {
+	d.closed++
+	if d.closeErr != nil {
+		return d.closeErr
+	}
+	return d.Database.Close()
+}
+
+// testErrorLogger records messages logged at error level.
+type testErrorLogger struct {
+	common.NullLogger
+	lock sync.Mutex
+	msgs []string
+}
+
+func (l *testErrorLogger) Error(msg string, ctx ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.msgs = append(l.msgs, msg)
+}
+
+func (s *ConsensusTestSuite) TestCloseDBOnStop() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(1)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	newDB := func() *testCloseCountDB {
+		dbInst, err := db.NewMemBackedDB()
+		s.Require().NoError(err)
+		return &testCloseCountDB{Database: dbInst}
+	}
+	// The database is left to callers by default.
+	dbInst := newDB()
+	_, con := s.prepareConsensusWithDB(
+		time.Now().UTC(), gov, prvKeys[0], conn, dbInst)
+	con.Stop()
+	s.Require().Equal(0, dbInst.closed)
+	// The database is closed only once even if Stop is called twice.
+	dbInst = newDB()
+	_, con = s.prepareConsensusWithDB(
+		time.Now().UTC(), gov, prvKeys[0], conn, dbInst)
+	con.CloseDBOnStop()
+	con.Stop()
+	con.Stop()
+	s.Require().Equal(1, dbInst.closed)
+	// Errors from closing the database are logged.
+	dbInst = newDB()
+	dbInst.closeErr = errors.New("close error")
+	logger := &testErrorLogger{}
+	nID := types.NewNodeID(prvKeys[0].PublicKey())
+	con = NewConsensus(time.Now().UTC(), test.NewApp(0, nil, nil), gov,
+		dbInst, conn.newNetwork(nID), prvKeys[0], logger)
+	conn.setCon(nID, con)
+	con.CloseDBOnStop()
+	con.Stop()
+	s.Require().Equal(1, dbInst.closed)
+	s.Require().Contains(logger.msgs, "Unable to close database")
+}
+
+func (s *ConsensusTestSuite) TestGetBlockByPosition() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(1)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	_, con := s.prepareConsensus(time.Now().UTC(), gov, prvKeys[0], conn)
+	_, err = con.GetBlockByPosition(types.Position{Height: 1})
+	s.Require().Equal(ErrBlockNotDelivered, err)
+	var parentHash common.Hash
+	blocks := make([]*types.Block, 0, 5)
+	for h := uint64(1); h <= 5; h++ {
+		b := &types.Block{
+			ParentHash: parentHash,
+			Hash:       common.NewRandomHash(),
+			Position:   types.Position{Height: h},
+		}
+		s.Require().NoError(con.db.PutBlock(*b))
+		s.Require().NoError(
+			con.db.PutCompactionChainTipInfo(b.Hash, b.Position.Height))
+		blocks = append(blocks, b)
+		parentHash = b.Hash
+	}
+	for _, b := range blocks {
+		got, err := con.GetBlockByPosition(b.Position)
+		s.Require().NoError(err)
+		s.Require().Equal(b.Hash, got.Hash)
+	}
+	_, err = con.GetBlockByPosition(types.Position{Height: 6})
+	s.Require().Equal(ErrBlockNotDelivered, err)
+	_, err = con.GetBlockByPosition(types.Position{Round: 1, Height: 2})
+	s.Require().Equal(db.ErrBlockDoesNotExist, err)
+}
+
+func (s *ConsensusTestSuite) TestProcessBlockBatch() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(2)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	dMoment := time.Now().UTC()
+	app, con := s.prepareConsensus(dMoment, gov, prvKeys[0], conn)
+	batchApp, batchCon := s.prepareConsensus(dMoment, gov, prvKeys[1], conn)
+	var parentHash common.Hash
+	blocks := make([]*types.Block, 0, 10)
+	for h := uint64(1); h <= 10; h++ {
+		b := &types.Block{
+			ParentHash: parentHash,
+			Hash:       common.NewRandomHash(),
+			Position:   types.Position{Height: h},
+		}
+		blocks = append(blocks, b)
+		parentHash = b.Hash
+	}
+	for _, b := range blocks {
+		s.Require().NoError(con.processBlock(b))
+	}
+	// Shuffle the batch and include blocks already processed.
+	s.Require().NoError(batchCon.processBlock(blocks[0]))
+	batch := append([]*types.Block{}, blocks...)
+	rand.Shuffle(len(batch), func(i, j int) {
+		batch[i], batch[j] = batch[j], batch[i]
+	})
+	batch = append(batch, blocks[2])
+	s.Require().NoError(batchCon.ProcessBlockBatch(batch))
+	s.waitDelivered(con)
+	s.waitDelivered(batchCon)
+	s.Require().Len(app.DeliverSequence, len(blocks))
+	s.Require().Equal(app.DeliverSequence, batchApp.DeliverSequence)
+	// Unsigned blocks are rejected once batches are verified.
+	verifyApp, verifyCon := s.prepareConsensus(
+		dMoment, gov, prvKeys[1], s.newNetworkConnection())
+	verifyCon.SetBatchVerifyWorkers(4)
+	s.Require().Equal(utils.ErrIncorrectHash,
+		verifyCon.ProcessBlockBatch(blocks))
+	s.waitDelivered(verifyCon)
+	s.Require().Empty(verifyApp.DeliverSequence)
+}
+
+func (s *ConsensusTestSuite) TestStallWatchdog() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(1)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	_, con := s.prepareConsensus(time.Now().UTC(), gov, prvKeys[0], conn)
+	now := time.Now().UTC()
+	con.clock = func() time.Time { return now }
+	con.SetStallThreshold(10 * time.Second)
+	atomic.StoreInt64(&con.lastConfirmedTime, now.UnixNano())
+	// Not stalled yet.
+	now = now.Add(9 * time.Second)
+	con.checkStall()
+	s.Require().Len(con.StallChan(), 0)
+	// Stalled.
+	now = now.Add(time.Second)
+	con.checkStall()
+	s.Require().Len(con.StallChan(), 1)
+	s.Require().Equal(con.baMgr.baModule.agreementID(), <-con.StallChan())
+	// Reported once per threshold.
+	now = now.Add(5 * time.Second)
+	con.checkStall()
+	s.Require().Len(con.StallChan(), 0)
+	now = now.Add(5 * time.Second)
+	con.checkStall()
+	s.Require().Len(con.StallChan(), 1)
+	<-con.StallChan()
+	// A confirmation resets the watchdog.
+	now = now.Add(9 * time.Second)
+	atomic.StoreInt64(&con.lastConfirmedTime, now.UnixNano())
+	now = now.Add(9 * time.Second)
+	con.checkStall()
+	s.Require().Len(con.StallChan(), 0)
+}
+
+func (s *ConsensusTestSuite) TestFinalityDepth() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(1)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	app, con := s.prepareConsensus(time.Now().UTC(), gov, prvKeys[0], conn)
+	con.SetFinalityDepth(2)
+	var parentHash common.Hash
+	blocks := make([]*types.Block, 0, 5)
+	for h := uint64(1); h <= 5; h++ {
+		b := &types.Block{
+			ParentHash: parentHash,
+			Hash:       common.NewRandomHash(),
+			Position:   types.Position{Height: h},
+		}
+		blocks = append(blocks, b)
+		parentHash = b.Hash
+	}
+	for i, b := range blocks {
+		s.Require().NoError(con.processBlock(b))
+		s.waitDelivered(con)
+		// Blocks are notified when two more blocks are delivered after them,
+		// and the compaction chain tip only follows notified blocks.
+		tipHash, tipHeight := con.db.GetCompactionChainTipInfo()
+		if i < 2 {
+			s.Require().Empty(app.DeliverSequence)
+			s.Require().Equal(common.Hash{}, tipHash)
+			_, err := con.deliveredBlockByHeight(b.Position.Height)
+			s.Require().Equal(ErrBlockNotDelivered, err)
+			continue
+		}
+		s.Require().Len(app.DeliverSequence, i-1)
+		s.Require().Equal(blocks[i-2].Hash, app.DeliverSequence[i-2])
+		s.Require().Equal(blocks[i-2].Hash, tipHash)
+		s.Require().Equal(blocks[i-2].Position.Height, tipHeight)
+	}
+	// Pending ones are notified in order when stopped.
+	con.Stop()
+	s.Require().Len(app.DeliverSequence, len(blocks))
+	for i, b := range blocks {
+		s.Require().Equal(b.Hash, app.DeliverSequence[i])
+	}
+}
+
+func (s *ConsensusTestSuite) TestNextBlock() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(1)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	dMoment := time.Now().UTC()
+	_, con := s.prepareConsensus(dMoment, gov, prvKeys[0], conn)
+	height, earliest, ready := con.NextBlock()
+	s.Require().True(ready)
+	s.Require().Equal(types.GenesisHeight, height)
+	s.Require().Equal(dMoment, earliest)
+	b := &types.Block{
+		Hash:      common.NewRandomHash(),
+		Position:  types.Position{Height: types.GenesisHeight},
+		Timestamp: dMoment.Add(time.Second),
+	}
+	s.Require().NoError(con.processBlock(b))
+	height, earliest, ready = con.NextBlock()
+	s.Require().True(ready)
+	s.Require().Equal(types.GenesisHeight+1, height)
+	s.Require().Equal(
+		b.Timestamp.Add(con.bcModule.configs[0].minBlockInterval), earliest)
+}
+
+func (s *ConsensusTestSuite) TestStandby() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(1)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	_, con := s.prepareConsensus(time.Now().UTC(), gov, prvKeys[0], conn)
+	hooked := make(chan *types.Block, 1)
+	con.SetBeforeBroadcastHook(func(b *types.Block) {
+		hooked <- b
+	})
+	con.SetStandby()
+	s.Require().True(con.Standby())
+	// Pretend to be a notary running BA for genesis block.
+	notarySet, err := con.nodeSetCache.GetNotarySet(0)
+	s.Require().NoError(err)
+	con.baMgr.recv.isNotary = true
+	con.baMgr.baModule.restart(notarySet, 1,
+		types.Position{Height: types.GenesisHeight}, con.ID, gov.CRS(0))
+	// Standby replica doesn't propose.
+	s.Require().Equal(types.NullBlockHash, con.baMgr.recv.ProposeBlock())
+	select {
+	case <-hooked:
+		s.Require().FailNow("standby replica proposes a block")
+	case <-time.After(100 * time.Millisecond):
+	}
+	// It proposes once promoted.
+	con.Promote()
+	s.Require().False(con.Standby())
+	hash := con.baMgr.recv.ProposeBlock()
+	s.Require().NotEqual(types.NullBlockHash, hash)
+	select {
+	case b := <-hooked:
+		s.Require().Equal(hash, b.Hash)
+	case <-time.After(5 * time.Second):
+		s.Require().FailNow("promoted replica doesn't propose")
+	}
+}
+
+func (s *ConsensusTestSuite) TestStandbyDeliverSequence() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(1)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, 100*time.Millisecond, &common.NullLogger{}, true),
+		ConfigRoundShift)
+	s.Require().NoError(err)
+	dMoment := time.Now().UTC()
+	nID := types.NewNodeID(pubKeys[0])
+	stream := make(chan types.Msg, 10000)
+	primaryApp := test.NewApp(0, nil, nil)
+	primaryDB, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	primary := NewConsensus(dMoment, primaryApp, gov, primaryDB,
+		&primaryNetwork{network: conn.newNetwork(nID), replica: stream},
+		prvKeys[0], &common.NullLogger{})
+	conn.setCon(nID, primary)
+	// The standby replica consumes the stream broadcast by the primary one.
+	standbyApp := test.NewApp(0, nil, nil)
+	standbyDB, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	standby := NewStandbyConsensus(dMoment, standbyApp, gov, standbyDB,
+		&standbyNetwork{network: conn.newNetwork(nID), recv: stream},
+		prvKeys[0], &common.NullLogger{})
+	s.Require().True(standby.Standby())
+	go primary.Run()
+	go standby.Run()
+	delivered := func(app *test.App) (n int) {
+		app.WithLock(func(app *test.App) {
+			n = len(app.DeliverSequence)
+		})
+		return
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for delivered(primaryApp) < 10 || delivered(standbyApp) < 10 {
+		s.Require().True(time.Now().Before(deadline))
+		time.Sleep(10 * time.Millisecond)
+	}
+	primary.Stop()
+	standby.Stop()
+	s.Require().NoError(primaryApp.Compare(standbyApp))
+}
+
+func (s *ConsensusTestSuite) TestValidateBlock() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(1)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	dMoment := time.Now().UTC()
+	_, con := s.prepareConsensus(dMoment, gov, prvKeys[0], conn)
+	b, err := con.bcModule.proposeBlock(
+		types.Position{Height: types.GenesisHeight},
+		dMoment.Add(time.Second), false)
+	s.Require().NoError(err)
+	s.Require().NoError(con.ValidateBlock(b))
+	// Validation should not change any state.
+	s.Require().Nil(con.bcModule.lastConfirmed)
+	s.Require().NoError(con.ValidateBlock(b))
+	// Tampered blocks should be rejected.
+	tampered := b.Clone()
+	tampered.Timestamp = tampered.Timestamp.Add(time.Second)
+	s.Require().Error(con.ValidateBlock(tampered))
+	tampered = b.Clone()
+	tampered.Signature = crypto.Signature{}
+	s.Require().Error(con.ValidateBlock(tampered))
+	tampered = b.Clone()
+	tampered.Payload = []byte("tampered")
+	s.Require().Equal(utils.ErrIncorrectHash, con.ValidateBlock(tampered))
+	tampered = b.Clone()
+	tampered.Position.Height++
+	s.Require().Equal(ErrNotGenesisBlock, con.ValidateBlock(tampered))
+}
+
+func (s *ConsensusTestSuite) TestBeforeBroadcastHook() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(1)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	_, con := s.prepareConsensus(time.Now().UTC(), gov, prvKeys[0], conn)
+	hooked := make(chan *types.Block, 1)
+	con.SetBeforeBroadcastHook(func(b *types.Block) {
+		hooked <- b
+	})
+	// Pretend to be a notary running BA for genesis block.
+	notarySet, err := con.nodeSetCache.GetNotarySet(0)
+	s.Require().NoError(err)
+	con.baMgr.recv.isNotary = true
+	con.baMgr.baModule.restart(notarySet, 1,
+		types.Position{Height: types.GenesisHeight}, con.ID, gov.CRS(0))
+	hash := con.baMgr.recv.ProposeBlock()
+	s.Require().NotEqual(types.NullBlockHash, hash)
+	select {
+	case b := <-hooked:
+		s.Require().Equal(hash, b.Hash)
+		s.Require().Equal(con.ID, b.ProposerID)
+	case <-time.After(5 * time.Second):
+		s.Require().FailNow("hook is not called")
+	}
+}
+
+// testBatchingApp records the first time payloads are prepared.
+type testBatchingApp struct {
+	*test.App
+	lock     sync.Mutex
+	prepared time.Time
+}
+
+func (app *testBatchingApp) PreparePayload(types.Position) ([]byte, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	if app.prepared.IsZero() {
+		app.prepared = time.Now()
+	}
+	return []byte{}, nil
+}
+
+func (app *testBatchingApp) firstPrepared() time.Time {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	return app.prepared
+}
+
+func (s *ConsensusTestSuite) TestProposalBatchWindow() {
+	conn := s.newNetworkConnection()
+	lambdaBA := time.Second
+	prvKeys, pubKeys, err := test.NewKeys(1)
+	s.Require().NoError(err)
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys, lambdaBA, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	dbInst, err := db.NewMemBackedDB()
+	s.Require().NoError(err)
+	app := &testBatchingApp{App: test.NewApp(0, nil, nil)}
+	nID := types.NewNodeID(prvKeys[0].PublicKey())
+	dMoment := time.Now().UTC()
+	con := NewConsensus(dMoment, app, gov, dbInst,
+		conn.newNetwork(nID), prvKeys[0], &common.NullLogger{})
+	conn.setCon(nID, con)
+	window := 400 * time.Millisecond
+	con.SetProposalBatchWindow(window)
+	go con.Run()
+	// BA is not locked while waiting for the window.
+	for time.Now().Before(dMoment.Add(window)) {
+		begin := time.Now()
+		func() {
+			con.baMgr.baModule.lock.Lock()
+			defer con.baMgr.baModule.lock.Unlock()
+		}()
+		s.Require().True(time.Since(begin) < window/2)
+		time.Sleep(window / 20)
+	}
+	// The block is proposed via BA after the window.
+	deadline := time.Now().Add(5 * lambdaBA)
+	for app.firstPrepared().IsZero() {
+		s.Require().True(time.Now().Before(deadline))
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.Require().False(app.firstPrepared().Before(dMoment.Add(window)))
+	con.Stop()
+	// The window is capped at half of lambdaBA.
+	con.SetProposalBatchWindow(lambdaBA)
+	s.Require().Equal(lambdaBA/2, con.baMgr.proposalBatchWindow(0))
+}
+
+func (s *ConsensusTestSuite) TestProcessVoteFromOutsider() {
+	conn := s.newNetworkConnection()
+	prvKeys, pubKeys, err := test.NewKeys(5)
+	s.Require().NoError(err)
+	// The last key is not in the node set.
+	gov, err := test.NewGovernance(test.NewState(DKGDelayRound,
+		pubKeys[:4], time.Second, &common.NullLogger{}, true), ConfigRoundShift)
+	s.Require().NoError(err)
+	_, con := s.prepareConsensus(time.Now().UTC(), gov, prvKeys[0], conn)
+	// Pretend to be a notary without running the consensus.
+	con.baMgr.recv.isNotary = true
+	pos := types.Position{Round: 0, Height: types.GenesisHeight}
+	vote := types.NewVote(types.VotePreCom, common.NewRandomHash(), 0)
+	vote.Position = pos
+	s.Require().NoError(utils.NewSigner(prvKeys[4]).SignVote(vote))
+	s.Require().Equal(ErrNotInNotarySet, con.ProcessVote(vote))
+	// Votes from notary set members are not rejected by the membership check.
+	vote = types.NewVote(types.VotePreCom, common.NewRandomHash(), 0)
+	vote.Position = pos
+	s.Require().NoError(utils.NewSigner(prvKeys[1]).SignVote(vote))
+	s.Require().NotEqual(ErrNotInNotarySet, con.ProcessVote(vote))
+}
+
 func TestConsensus(t *testing.T) {
 	suite.Run(t, new(ConsensusTestSuite))
 }
@@ -83,7 +83,11 @@ func (prv *PrivateKey) MarshalJSON() ([]byte, error) {
 
 // UnmarshalJSON implements json.Unmarshaller.
 func (prv *PrivateKey) UnmarshalJSON(data []byte) error {
-	return json.Unmarshal(data, &prv.privateKey)
+	if err := json.Unmarshal(data, &prv.privateKey); err != nil {
+		return err
+	}
+	prv.publicKey = *newPublicKey(&prv.privateKey)
+	return nil
 }
 
 // ID is the id for DKG protocol.
@@ -415,6 +419,7 @@ func (prvs *PrivateKeyShares) RecoverPrivateKey(qualifyIDs IDs) (
 		}
 		prv.privateKey.Add(&prvs.shares[idx].privateKey)
 	}
+	prv.publicKey = *newPublicKey(&prv.privateKey)
 	return &prv, nil
 }
 
@@ -601,6 +606,23 @@ func (prv *PrivateKey) SetBytes(bytes []byte) error {
 	return nil
 }
 
+// Clear zeroes the private key, it should not be used after cleared.
+func (prv *PrivateKey) Clear() {
+	prv.privateKey = bls.SecretKey{}
+	prv.publicKey = PublicKey{}
+}
+
+// Clear zeroes all shares and the master private key, it should not be used
+// after cleared.
+func (prvs *PrivateKeyShares) Clear() {
+	for i := range prvs.shares {
+		prvs.shares[i].Clear()
+	}
+	for i := range prvs.masterPrivateKey {
+		prvs.masterPrivateKey[i] = bls.SecretKey{}
+	}
+}
+
 // String returns string representation of privat key.
 func (prv *PrivateKey) String() string {
 	return prv.privateKey.GetHexString()
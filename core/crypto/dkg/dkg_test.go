@@ -278,6 +278,15 @@ func (s *DKGTestSuite) TestDKGProtocol() {
 	groupPK := RecoverGroupPublicKey(pubShares)
 	s.True(groupPK.VerifySignature(hash, recoverSig1))
 	s.True(groupPK.VerifySignature(hash, recoverSig2))
+	// Public keys of recovered private keys should be ready.
+	for _, member := range members {
+		prvKey, err := member.receivedPrvShares.RecoverPrivateKey(
+			collectIDs(qualify))
+		s.Require().NoError(err)
+		sig, err := prvKey.Sign(hash)
+		s.Require().NoError(err)
+		s.True(prvKey.PublicKey().VerifySignature(hash, sig))
+	}
 }
 
 func (s *DKGTestSuite) TestSignature() {
@@ -293,6 +302,30 @@ func (s *DKGTestSuite) TestSignature() {
 	s.False(pubKey.VerifySignature(hash, sig))
 }
 
+func (s *DKGTestSuite) TestRecoverPublicKeyFromShares() {
+	k := 3
+	IDs := s.genID(5)
+	_, pubShares := NewPrivateKeyShares(k)
+	pubs := make([]*PublicKey, 0, len(IDs))
+	for _, ID := range IDs {
+		pub, err := pubShares.Share(ID)
+		s.Require().NoError(err)
+		pubs = append(pubs, pub)
+	}
+	groupPK := RecoverGroupPublicKey([]*PublicKeyShares{pubShares})
+	for _, n := range []int{k, len(IDs)} {
+		recovered, err := RecoverPublicKeyFromShares(pubs[:n], IDs[:n])
+		s.Require().NoError(err)
+		s.Equal(groupPK.Bytes(), recovered.Bytes())
+	}
+	// Not enough shares.
+	recovered, err := RecoverPublicKeyFromShares(pubs[:k-1], IDs[:k-1])
+	s.Require().NoError(err)
+	s.NotEqual(groupPK.Bytes(), recovered.Bytes())
+	_, err = RecoverPublicKeyFromShares(nil, nil)
+	s.Equal(ErrNoIDToRecover, err)
+}
+
 func (s *DKGTestSuite) TestPrivateKeyRLPEncodeDecode() {
 	k := NewPrivateKey()
 	b, err := rlp.EncodeToBytes(k)
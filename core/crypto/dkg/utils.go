@@ -72,6 +72,24 @@ func RecoverGroupPublicKey(pubShares []*PublicKeyShares) *PublicKey {
 	return pub
 }
 
+// RecoverPublicKeyFromShares recovers the public key whose private key is
+// shared by public key shares of IDs, which should be at least threshold.
+func RecoverPublicKeyFromShares(pubs []*PublicKey, IDs IDs) (
+	*PublicKey, error) {
+	if len(IDs) == 0 {
+		return nil, ErrNoIDToRecover
+	}
+	blsPubs := make([]bls.PublicKey, len(pubs))
+	for i, pub := range pubs {
+		blsPubs[i] = pub.publicKey
+	}
+	var pub PublicKey
+	if err := pub.publicKey.Recover(blsPubs, []bls.ID(IDs)); err != nil {
+		return nil, err
+	}
+	return &pub, nil
+}
+
 // NewRandomPrivateKeyShares constructs a private key shares randomly.
 func NewRandomPrivateKeyShares() *PrivateKeyShares {
 	// Generate IDs.
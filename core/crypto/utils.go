@@ -51,9 +51,14 @@ func Keccak256Hash(data ...[]byte) (h common.Hash) {
 
 // Clone returns a deep copy of a signature.
 func (sig Signature) Clone() Signature {
+	var bytes []byte
+	if sig.Signature != nil {
+		bytes = make([]byte, len(sig.Signature))
+		copy(bytes, sig.Signature)
+	}
 	return Signature{
 		Type:      sig.Type,
-		Signature: sig.Signature[:],
+		Signature: bytes,
 	}
 }
 
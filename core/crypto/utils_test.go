@@ -47,6 +47,17 @@ func (s *CryptoTestSuite) TestHash() {
 	}
 }
 
+func (s *CryptoTestSuite) TestSignatureClone() {
+	sig := Signature{Type: "bls", Signature: []byte{1, 2, 3}}
+	copied := sig.Clone()
+	s.Equal(sig, copied)
+	copied.Signature[0] = 4
+	s.Equal(byte(1), sig.Signature[0])
+	// Empty signatures are kept as they are.
+	s.Nil(Signature{}.Clone().Signature)
+	s.NotNil(Signature{Signature: []byte{}}.Clone().Signature)
+}
+
 func TestCrypto(t *testing.T) {
 	suite.Run(t, new(CryptoTestSuite))
 }
@@ -78,6 +78,10 @@ type Reader interface {
 	// of the tip block of compaction chain. Empty hash and zero height means
 	// the compaction chain is empty.
 	GetCompactionChainTipInfo() (common.Hash, uint64)
+	// GetCompactionChainBlockHash returns the hash of the block at a height
+	// of compaction chain, which is indexed when it becomes the tip.
+	// ErrBlockDoesNotExist is returned if no block is indexed at that height.
+	GetCompactionChainBlockHash(height uint64) (common.Hash, error)
 
 	// DKG Private Key related methods.
 	GetDKGPrivateKey(round, reset uint64) (dkg.PrivateKey, error)
@@ -90,6 +94,9 @@ type Writer interface {
 	PutBlock(block types.Block) error
 	PutCompactionChainTipInfo(common.Hash, uint64) error
 	PutDKGPrivateKey(round, reset uint64, pk dkg.PrivateKey) error
+	// DeleteDKGPrivateKey removes the DKG private key of one round, it's not
+	// an error if there is none.
+	DeleteDKGPrivateKey(round uint64) error
 	PutOrUpdateDKGProtocol(dkgProtocol DKGProtocolInfo) error
 }
 
@@ -32,6 +32,7 @@ import (
 var (
 	blockKeyPrefix            = []byte("b-")
 	compactionChainTipInfoKey = []byte("cc-tip")
+	compactionChainKeyPrefix  = []byte("cc-h")
 	dkgPrivateKeyKeyPrefix    = []byte("dkg-prvs")
 	dkgProtocolInfoKeyPrefix  = []byte("dkg-protocol-info")
 )
@@ -455,7 +456,10 @@ func (lvl *LevelDBBackedDB) PutCompactionChainTipInfo(
 	if info.Height+1 != height {
 		return ErrInvalidCompactionChainTipHeight
 	}
-	return lvl.db.Put(compactionChainTipInfoKey, marshaled, nil)
+	batch := new(leveldb.Batch)
+	batch.Put(lvl.getCompactionChainKey(height), blockHash[:])
+	batch.Put(compactionChainTipInfoKey, marshaled)
+	return lvl.db.Write(batch, nil)
 }
 
 func (lvl *LevelDBBackedDB) internalGetCompactionChainTipInfo() (
@@ -483,6 +487,21 @@ func (lvl *LevelDBBackedDB) GetCompactionChainTipInfo() (
 	return
 }
 
+// GetCompactionChainBlockHash get the hash of the block at a height of
+// compaction chain.
+func (lvl *LevelDBBackedDB) GetCompactionChainBlockHash(height uint64) (
+	hash common.Hash, err error) {
+	queried, err := lvl.db.Get(lvl.getCompactionChainKey(height), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			err = ErrBlockDoesNotExist
+		}
+		return
+	}
+	copy(hash[:], queried)
+	return
+}
+
 // GetDKGPrivateKey get DKG private key of one round.
 func (lvl *LevelDBBackedDB) GetDKGPrivateKey(round, reset uint64) (
 	prv dkg.PrivateKey, err error) {
@@ -526,6 +545,11 @@ func (lvl *LevelDBBackedDB) PutDKGPrivateKey(
 		lvl.getDKGPrivateKeyKey(round), marshaled, nil)
 }
 
+// DeleteDKGPrivateKey removes DKG private key of one round.
+func (lvl *LevelDBBackedDB) DeleteDKGPrivateKey(round uint64) error {
+	return lvl.db.Delete(lvl.getDKGPrivateKeyKey(round), nil)
+}
+
 // GetDKGProtocol get DKG protocol.
 func (lvl *LevelDBBackedDB) GetDKGProtocol() (
 	info DKGProtocolInfo, err error) {
@@ -557,6 +581,15 @@ func (lvl *LevelDBBackedDB) getBlockKey(hash common.Hash) (ret []byte) {
 	return
 }
 
+func (lvl *LevelDBBackedDB) getCompactionChainKey(
+	height uint64) (ret []byte) {
+	ret = make([]byte, len(compactionChainKeyPrefix)+8)
+	copy(ret, compactionChainKeyPrefix)
+	binary.LittleEndian.PutUint64(
+		ret[len(compactionChainKeyPrefix):], height)
+	return
+}
+
 func (lvl *LevelDBBackedDB) getDKGPrivateKeyKey(
 	round uint64) (ret []byte) {
 	ret = make([]byte, len(dkgPrivateKeyKeyPrefix)+8)
@@ -155,7 +155,17 @@ func (s *LevelDBTestSuite) TestCompactionChainTipInfo() {
 	err = dbInst.PutCompactionChainTipInfo(hash, 3)
 	s.Require().Equal(err.Error(), ErrInvalidCompactionChainTipHeight.Error())
 	// It's OK to put compaction chain tip info with height incremental by 1.
-	s.Require().NoError(dbInst.PutCompactionChainTipInfo(hash, 2))
+	hash2 := common.NewRandomHash()
+	s.Require().NoError(dbInst.PutCompactionChainTipInfo(hash2, 2))
+	// Blocks on compaction chain are indexed by height.
+	hashBack, err = dbInst.GetCompactionChainBlockHash(1)
+	s.Require().NoError(err)
+	s.Require().Equal(hash, hashBack)
+	hashBack, err = dbInst.GetCompactionChainBlockHash(2)
+	s.Require().NoError(err)
+	s.Require().Equal(hash2, hashBack)
+	_, err = dbInst.GetCompactionChainBlockHash(3)
+	s.Require().Equal(err.Error(), ErrBlockDoesNotExist.Error())
 }
 
 func (s *LevelDBTestSuite) TestDKGPrivateKey() {
@@ -194,6 +204,11 @@ func (s *LevelDBTestSuite) TestDKGPrivateKey() {
 	s.Require().NoError(err)
 	s.Require().Equal(bytes.Compare(p2.Bytes(), tmpPrv.Bytes()), 0)
 	s.Require().NotEqual(bytes.Compare(p2.Bytes(), p.Bytes()), 0)
+	// Delete it, deleting a non-existing one is fine.
+	s.Require().NoError(dbInst.DeleteDKGPrivateKey(1))
+	_, err = dbInst.GetDKGPrivateKey(1, 1)
+	s.Require().Equal(err.Error(), ErrDKGPrivateKeyDoesNotExist.Error())
+	s.Require().NoError(dbInst.DeleteDKGPrivateKey(2))
 }
 
 func (s *LevelDBTestSuite) TestDKGProtocol() {
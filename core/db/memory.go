@@ -48,6 +48,7 @@ type MemBackedDB struct {
 	compactionChainTipLock   sync.RWMutex
 	compactionChainTipHash   common.Hash
 	compactionChainTipHeight uint64
+	compactionChainHashes    map[uint64]common.Hash
 	dkgPrivateKeysLock       sync.RWMutex
 	dkgPrivateKeys           map[uint64]*dkgPrivateKey
 	dkgProtocolLock          sync.RWMutex
@@ -59,9 +60,10 @@ type MemBackedDB struct {
 func NewMemBackedDB(persistantFilePath ...string) (
 	dbInst *MemBackedDB, err error) {
 	dbInst = &MemBackedDB{
-		blockHashSequence: common.Hashes{},
-		blocksByHash:      make(map[common.Hash]*types.Block),
-		dkgPrivateKeys:    make(map[uint64]*dkgPrivateKey),
+		blockHashSequence:     common.Hashes{},
+		blocksByHash:          make(map[common.Hash]*types.Block),
+		compactionChainHashes: make(map[uint64]common.Hash),
+		dkgPrivateKeys:        make(map[uint64]*dkgPrivateKey),
 	}
 	if len(persistantFilePath) == 0 || len(persistantFilePath[0]) == 0 {
 		return
@@ -156,6 +158,7 @@ func (m *MemBackedDB) PutCompactionChainTipInfo(
 	}
 	m.compactionChainTipHeight = height
 	m.compactionChainTipHash = blockHash
+	m.compactionChainHashes[height] = blockHash
 	return nil
 }
 
@@ -168,6 +171,19 @@ func (m *MemBackedDB) GetCompactionChainTipInfo() (
 	return m.compactionChainTipHash, m.compactionChainTipHeight
 }
 
+// GetCompactionChainBlockHash get the hash of the block at a height of
+// compaction chain.
+func (m *MemBackedDB) GetCompactionChainBlockHash(height uint64) (
+	common.Hash, error) {
+	m.compactionChainTipLock.RLock()
+	defer m.compactionChainTipLock.RUnlock()
+	hash, exists := m.compactionChainHashes[height]
+	if !exists {
+		return common.Hash{}, ErrBlockDoesNotExist
+	}
+	return hash, nil
+}
+
 // GetDKGPrivateKey get DKG private key of one round.
 func (m *MemBackedDB) GetDKGPrivateKey(round, reset uint64) (
 	dkg.PrivateKey, error) {
@@ -194,6 +210,14 @@ func (m *MemBackedDB) PutDKGPrivateKey(
 	return nil
 }
 
+// DeleteDKGPrivateKey removes DKG private key of one round.
+func (m *MemBackedDB) DeleteDKGPrivateKey(round uint64) error {
+	m.dkgPrivateKeysLock.Lock()
+	defer m.dkgPrivateKeysLock.Unlock()
+	delete(m.dkgPrivateKeys, round)
+	return nil
+}
+
 // GetDKGProtocol get DKG protocol.
 func (m *MemBackedDB) GetDKGProtocol() (
 	DKGProtocolInfo, error) {
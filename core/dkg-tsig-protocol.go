@@ -50,6 +50,8 @@ var (
 		"not enough of partial signatures")
 	ErrRoundAlreadyPurged = fmt.Errorf(
 		"cache of round already been purged")
+	ErrPurgedRoundNotAvailable = fmt.Errorf(
+		"cache of round already been purged and not available in governance")
 	ErrTSigNotReady = fmt.Errorf(
 		"tsig not ready")
 	ErrSelfMPKNotRegister = fmt.Errorf(
@@ -58,6 +60,8 @@ var (
 		"unable to get self DKG PrivateShare")
 	ErrSelfPrvShareMismatch = fmt.Errorf(
 		"self privateShare does not match mpk registered")
+	ErrUnableDecryptPrvShare = fmt.Errorf(
+		"unable to decrypt DKG PrivateShare")
 )
 
 // ErrUnexpectedDKGResetCount represents receiving a DKG message with unexpected
@@ -107,6 +111,21 @@ type dkgReceiver interface {
 	ProposeDKGSuccess(final *typesDKG.Success)
 }
 
+// DKGStats counts complaints observed by this node during the DKG protocol of
+// one round, they are accumulated across DKG resets of that round.
+type DKGStats struct {
+	Round uint64
+	Reset uint64
+	// Complaints is the count of complaints proposed against invalid private
+	// shares.
+	Complaints int
+	// NackComplaints is the count of nack complaints proposed.
+	NackComplaints int
+	// AntiComplaints is the count of anti nack complaints proposed or
+	// rebroadcasted.
+	AntiComplaints int
+}
+
 type dkgProtocol struct {
 	ID                 types.NodeID
 	recv               dkgReceiver
@@ -119,10 +138,16 @@ type dkgProtocol struct {
 	prvShares          *dkg.PrivateKeyShares
 	prvSharesReceived  map[types.NodeID]struct{}
 	nodeComplained     map[types.NodeID]struct{}
-	// Complaint[from][to]'s anti is saved to antiComplaint[from][to].
+	// Complaint[from][to]'s anti is saved to antiComplaint[from][to], both
+	// of them should be DKG participants, thus it's bounded by the square of
+	// the count of participants.
 	antiComplaintReceived map[types.NodeID]map[types.NodeID]struct{}
 	// The completed step in `runDKG`.
 	step int
+	// cipher decrypts private shares sent to this node, it's nil when
+	// private shares are sent in plaintext.
+	cipher DKGShareCipher
+	stats  *DKGStats
 }
 
 func (d *dkgProtocol) convertFromInfo(info db.DKGProtocolInfo) {
@@ -204,9 +229,21 @@ type TSigVerifierCacheInterface interface {
 	IsDKGFinal(round uint64) bool
 }
 
+// TSigVerifierStore persists group public keys derived by TSigVerifierCache,
+// thus they don't have to be derived again after restarting. Only group
+// public keys of final DKG are stored, they never change once stored.
+type TSigVerifierStore interface {
+	// Load returns the stored group public key of a round.
+	Load(round uint64) (*typesDKG.GroupPublicKey, bool)
+
+	// Store saves the group public key of a round.
+	Store(round uint64, gpk *typesDKG.GroupPublicKey)
+}
+
 // TSigVerifierCache is the cache for TSigVerifier.
 type TSigVerifierCache struct {
 	intf      TSigVerifierCacheInterface
+	store     TSigVerifierStore
 	verifier  map[uint64]TSigVerifier
 	minRound  uint64
 	cacheSize int
@@ -227,29 +264,12 @@ func newDKGProtocol(
 	reset uint64,
 	threshold int) *dkgProtocol {
 
-	prvShare, pubShare := dkg.NewPrivateKeyShares(threshold)
-
-	recv.ProposeDKGMasterPublicKey(&typesDKG.MasterPublicKey{
-		Round:           round,
-		Reset:           reset,
-		DKGID:           typesDKG.NewID(ID),
-		PublicKeyShares: *pubShare.Move(),
-	})
-
-	return &dkgProtocol{
-		ID:                    ID,
-		recv:                  recv,
-		round:                 round,
-		reset:                 reset,
-		threshold:             threshold,
-		idMap:                 make(map[types.NodeID]dkg.ID),
-		mpkMap:                make(map[types.NodeID]*dkg.PublicKeyShares),
-		masterPrivateShare:    prvShare,
-		prvShares:             dkg.NewEmptyPrivateKeyShares(),
-		prvSharesReceived:     make(map[types.NodeID]struct{}),
-		nodeComplained:        make(map[types.NodeID]struct{}),
-		antiComplaintReceived: make(map[types.NodeID]map[types.NodeID]struct{}),
+	d := &dkgProtocol{
+		ID:   ID,
+		recv: recv,
 	}
+	d.resetRoundState(round, reset, threshold)
+	return d
 }
 
 func recoverDKGProtocol(
@@ -267,7 +287,8 @@ func recoverDKGProtocol(
 	}
 
 	dkgProtocol := dkgProtocol{
-		recv: recv,
+		recv:  recv,
+		stats: &DKGStats{Round: round, Reset: reset},
 	}
 	dkgProtocol.convertFromInfo(dkgProtocolInfo)
 
@@ -351,6 +372,7 @@ func (d *dkgProtocol) proposeNackComplaints() {
 				Reset:      d.reset,
 			},
 		})
+		d.stats.NackComplaints++
 	}
 }
 
@@ -386,6 +408,7 @@ func (d *dkgProtocol) processNackComplaints(complaints []*typesDKG.Complaint) (
 			Reset:        d.reset,
 			PrivateShare: *share,
 		})
+		d.stats.AntiComplaints++
 	}
 	return
 }
@@ -422,6 +445,7 @@ func (d *dkgProtocol) enforceNackComplaints(complaints []*typesDKG.Complaint) {
 					Reset:      d.reset,
 				},
 			})
+			d.stats.NackComplaints++
 		}
 	}
 }
@@ -483,13 +507,16 @@ func (d *dkgProtocol) processPrivateShare(
 		}
 		d.nodeComplained[prvShare.ProposerID] = struct{}{}
 		d.recv.ProposeDKGComplaint(complaint)
+		d.stats.Complaints++
 	} else if prvShare.ReceiverID == d.ID {
 		sender := d.idMap[prvShare.ProposerID]
 		if err := d.prvShares.AddShare(sender, &prvShare.PrivateShare); err != nil {
 			return err
 		}
 	} else {
-		// The prvShare is an anti complaint.
+		// The prvShare is an anti complaint. Both its proposer and receiver
+		// are checked to be DKG participants above, entries referencing
+		// unknown IDs would never be allocated.
 		if _, exist := d.antiComplaintReceived[prvShare.ReceiverID]; !exist {
 			d.antiComplaintReceived[prvShare.ReceiverID] =
 				make(map[types.NodeID]struct{})
@@ -497,6 +524,7 @@ func (d *dkgProtocol) processPrivateShare(
 		if _, exist :=
 			d.antiComplaintReceived[prvShare.ReceiverID][prvShare.ProposerID]; !exist {
 			d.recv.ProposeDKGAntiNackComplaint(prvShare)
+			d.stats.AntiComplaints++
 			d.antiComplaintReceived[prvShare.ReceiverID][prvShare.ProposerID] =
 				struct{}{}
 		}
@@ -504,6 +532,57 @@ func (d *dkgProtocol) processPrivateShare(
 	return nil
 }
 
+// resetRoundState clears all states collected in the previous round and
+// proposes a new master public key for round and reset. The master public keys
+// of the new round should be processed before this instance is reused.
+func (d *dkgProtocol) resetRoundState(round, reset uint64, threshold int) {
+	prvShare, pubShare := dkg.NewPrivateKeyShares(threshold)
+
+	d.recv.ProposeDKGMasterPublicKey(&typesDKG.MasterPublicKey{
+		Round:           round,
+		Reset:           reset,
+		DKGID:           typesDKG.NewID(d.ID),
+		PublicKeyShares: *pubShare.Move(),
+	})
+
+	d.round = round
+	d.reset = reset
+	d.threshold = threshold
+	d.idMap = make(map[types.NodeID]dkg.ID)
+	d.mpkMap = make(map[types.NodeID]*dkg.PublicKeyShares)
+	d.masterPrivateShare = prvShare
+	d.prvShares = dkg.NewEmptyPrivateKeyShares()
+	d.prvSharesReceived = make(map[types.NodeID]struct{})
+	d.nodeComplained = make(map[types.NodeID]struct{})
+	d.antiComplaintReceived = make(map[types.NodeID]map[types.NodeID]struct{})
+	d.step = 0
+	d.stats = &DKGStats{Round: round, Reset: reset}
+}
+
+// processEncryptedPrivateShare decrypts a private share sent to this node,
+// and processes it like a plaintext one, including verifying its signature.
+func (d *dkgProtocol) processEncryptedPrivateShare(
+	encrypted *typesDKG.EncryptedPrivateShare) error {
+	if d.cipher == nil || encrypted.ReceiverID != d.ID {
+		return ErrUnableDecryptPrvShare
+	}
+	share, err := d.cipher.DecryptDKGShare(encrypted.EncryptedShare)
+	if err != nil {
+		return err
+	}
+	prvShare := &typesDKG.PrivateShare{
+		ProposerID: encrypted.ProposerID,
+		ReceiverID: encrypted.ReceiverID,
+		Round:      encrypted.Round,
+		Reset:      encrypted.Reset,
+		Signature:  encrypted.Signature,
+	}
+	if err := prvShare.PrivateShare.SetBytes(share); err != nil {
+		return err
+	}
+	return d.processPrivateShare(prvShare)
+}
+
 func (d *dkgProtocol) proposeMPKReady() {
 	d.recv.ProposeDKGMPKReady(&typesDKG.MPKReady{
 		ProposerID: d.ID,
@@ -558,10 +637,21 @@ func NewTSigVerifierCache(
 	}
 }
 
-// UpdateAndGet calls Update and then Get.
+// SetStore makes this cache load group public keys from the store before
+// deriving them from governance, and save those derived to the store. It
+// should be called before this cache is used.
+func (tc *TSigVerifierCache) SetStore(store TSigVerifierStore) {
+	tc.store = store
+}
+
+// UpdateAndGet calls Update and then Get. The verifier of a purged round
+// would be reconstructed from governance without caching it.
 func (tc *TSigVerifierCache) UpdateAndGet(round uint64) (
 	TSigVerifier, bool, error) {
 	ok, err := tc.Update(round)
+	if err == ErrRoundAlreadyPurged {
+		return tc.fetchPurged(round)
+	}
 	if err != nil {
 		return nil, false, err
 	}
@@ -589,20 +679,74 @@ func (tc *TSigVerifierCache) Update(round uint64) (bool, error) {
 	if _, exist := tc.verifier[round]; exist {
 		return true, nil
 	}
+	if tc.store != nil {
+		if gpk, exist := tc.store.Load(round); exist {
+			tc.addWithoutLock(round, gpk)
+			return true, nil
+		}
+	}
 	if !tc.intf.IsDKGFinal(round) {
 		return false, nil
 	}
+	gpk, err := tc.newGroupPublicKey(round)
+	if err != nil {
+		return false, err
+	}
+	tc.addWithoutLock(round, gpk)
+	return true, nil
+}
+
+func (tc *TSigVerifierCache) fetchPurged(round uint64) (
+	TSigVerifier, bool, error) {
+	if tc.store != nil {
+		if gpk, exist := tc.store.Load(round); exist {
+			return gpk, true, nil
+		}
+	}
+	if !tc.intf.IsDKGFinal(round) {
+		return nil, false, ErrPurgedRoundNotAvailable
+	}
+	gpk, err := tc.newGroupPublicKey(round)
+	if err != nil {
+		return nil, false, err
+	}
+	return gpk, true, nil
+}
+
+// newGroupPublicKey derives the group public key of a round from governance,
+// it's saved to the store if any.
+func (tc *TSigVerifierCache) newGroupPublicKey(round uint64) (
+	*typesDKG.GroupPublicKey, error) {
 	gpk, err := typesDKG.NewGroupPublicKey(round,
 		tc.intf.DKGMasterPublicKeys(round),
 		tc.intf.DKGComplaints(round),
 		utils.GetDKGThreshold(utils.GetConfigWithPanic(tc.intf, round, nil)))
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	if tc.store != nil {
+		tc.store.Store(round, gpk)
 	}
+	return gpk, nil
+}
+
+// Add a verifier of a round directly, instead of getting it from governance.
+func (tc *TSigVerifierCache) Add(round uint64, verifier TSigVerifier) error {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+	if round < tc.minRound {
+		return ErrRoundAlreadyPurged
+	}
+	tc.addWithoutLock(round, verifier)
+	return nil
+}
+
+func (tc *TSigVerifierCache) addWithoutLock(
+	round uint64, verifier TSigVerifier) {
 	if len(tc.verifier) == 0 {
 		tc.minRound = round
 	}
-	tc.verifier[round] = gpk
+	tc.verifier[round] = verifier
 	if len(tc.verifier) > tc.cacheSize {
 		delete(tc.verifier, tc.minRound)
 	}
@@ -613,7 +757,6 @@ func (tc *TSigVerifierCache) Update(round uint64) (bool, error) {
 			break
 		}
 	}
-	return true, nil
 }
 
 // Delete the cache of given round.
@@ -631,6 +774,22 @@ func (tc *TSigVerifierCache) Get(round uint64) (TSigVerifier, bool) {
 	return verifier, exist
 }
 
+// GroupPublicKey returns the DKG group public key of round, it's useful to
+// compare DKG results across nodes. Verifiers added by Add without a group
+// public key are treated as not existing.
+func (tc *TSigVerifierCache) GroupPublicKey(round uint64) (
+	*dkg.PublicKey, bool) {
+	verifier, exist := tc.Get(round)
+	if !exist {
+		return nil, false
+	}
+	gpk, ok := verifier.(*typesDKG.GroupPublicKey)
+	if !ok || gpk.GroupPublicKey == nil {
+		return nil, false
+	}
+	return gpk.GroupPublicKey, true
+}
+
 func newTSigProtocol(
 	npks *typesDKG.NodePublicKeys,
 	hash common.Hash) *tsigProtocol {
@@ -107,6 +107,32 @@ func (r *testDKGReceiver) ProposeDKGSuccess(success *typesDKG.Success) {
 	r.success = append(r.success, success)
 }
 
+// testDKGShareCipher "encrypts" shares by flipping all their bits.
+type testDKGShareCipher struct{}
+
+func (testDKGShareCipher) flip(data []byte) []byte {
+	ret := make([]byte, len(data))
+	for i := range data {
+		ret[i] = ^data[i]
+	}
+	return ret
+}
+
+func (c testDKGShareCipher) EncryptDKGShare(
+	_ crypto.PublicKey, share []byte) ([]byte, error) {
+	return c.flip(share), nil
+}
+
+func (c testDKGShareCipher) DecryptDKGShare(
+	encrypted []byte) ([]byte, error) {
+	return c.flip(encrypted), nil
+}
+
+// SendDKGEncryptedPrivateShare is not used in DKG protocol.
+func (c testDKGShareCipher) SendDKGEncryptedPrivateShare(
+	crypto.PublicKey, *typesDKG.EncryptedPrivateShare) {
+}
+
 func (s *DKGTSIGProtocolTestSuite) setupDKGParticipants(n int) {
 	s.nIDs = make(types.NodeIDs, 0, n)
 	s.signers = make(map[types.NodeID]*utils.Signer, n)
@@ -516,6 +542,7 @@ func (s *DKGTSIGProtocolTestSuite) TestComplaint() {
 	delete(receiver.complaints, byzantineID)
 	s.Require().NoError(protocol.processPrivateShare(invalidShare))
 	s.Len(receiver.complaints, 0)
+	s.Equal(1, protocol.stats.Complaints)
 }
 
 // TestDuplicateComplaint tests if the duplicated complaint is process properly.
@@ -670,6 +697,15 @@ func (s *DKGTSIGProtocolTestSuite) TestEncorceNackComplaint() {
 	protocols[thirdPerson].enforceNackComplaints([]*typesDKG.Complaint{complaint})
 	_, exist = receivers[thirdPerson].complaints[byzantineID]
 	s.Require().False(exist)
+
+	// Check stats of complaints.
+	s.Equal(len(receivers[targetID].complaints),
+		protocols[targetID].stats.NackComplaints)
+	s.Equal(1, protocols[thirdPerson].stats.NackComplaints)
+	s.Equal(1, protocols[thirdPerson].stats.AntiComplaints)
+	s.Equal(1, protocols[byzantineID].stats.AntiComplaints)
+	s.Equal(round, protocols[byzantineID].stats.Round)
+	s.Equal(reset, protocols[byzantineID].stats.Reset)
 }
 
 // TestQualifyIDs tests if there is a id with t+1 nack complaints
@@ -955,14 +991,23 @@ func (s *DKGTSIGProtocolTestSuite) TestTSigVerifierCache() {
 		round := uint64(i + 1)
 		_, exist := cache.Get(round)
 		s.True(exist)
+		gpk, exist := cache.GroupPublicKey(round)
+		s.Require().True(exist)
+		gpk2, err := typesDKG.NewGroupPublicKey(round,
+			gov.DKGMasterPublicKeys(round), gov.DKGComplaints(round),
+			utils.GetDKGThreshold(gov.Configuration(round)))
+		s.Require().NoError(err)
+		s.Require().Equal(gpk2.GroupPublicKey.Bytes(), gpk.Bytes())
 	}
+	_, exist := cache.GroupPublicKey(uint64(1))
+	s.False(exist)
 
 	ok, err := cache.Update(uint64(1))
 	s.Require().Equal(ErrRoundAlreadyPurged, err)
 
 	cache.Delete(uint64(5))
 	s.Len(cache.verifier, 2)
-	_, exist := cache.Get(uint64(5))
+	_, exist = cache.Get(uint64(5))
 	s.False(exist)
 
 	cache = NewTSigVerifierCache(gov, 1)
@@ -979,6 +1024,89 @@ func (s *DKGTSIGProtocolTestSuite) TestTSigVerifierCache() {
 	cache.Purge(5)
 	s.Require().Len(cache.verifier, 0)
 	s.Require().Equal(uint64(5), cache.minRound)
+
+	// Verifiers of purged rounds could still be reconstructed from governance,
+	// without being cached.
+	v, ok, err := cache.UpdateAndGet(uint64(2))
+	s.Require().NoError(err)
+	s.Require().True(ok)
+	s.Require().NotNil(v)
+	s.Require().Len(cache.verifier, 0)
+	s.Require().Equal(uint64(5), cache.minRound)
+	s.Require().NoError(cache.Add(uint64(20), v))
+	_, ok, err = cache.UpdateAndGet(uint64(11))
+	s.Require().Equal(ErrPurgedRoundNotAvailable, err)
+	s.Require().False(ok)
+}
+
+type testTSigVerifierStore struct {
+	gpks   map[uint64]*typesDKG.GroupPublicKey
+	stored int
+}
+
+func (st *testTSigVerifierStore) Load(round uint64) (
+	*typesDKG.GroupPublicKey, bool) {
+	gpk, exist := st.gpks[round]
+	return gpk, exist
+}
+
+func (st *testTSigVerifierStore) Store(
+	round uint64, gpk *typesDKG.GroupPublicKey) {
+	st.gpks[round] = gpk
+	st.stored++
+}
+
+func (s *DKGTSIGProtocolTestSuite) TestTSigVerifierStore() {
+	k := 3
+	n := 10
+	round := uint64(1)
+	reset := uint64(0)
+	_, pubKeys, err := test.NewKeys(n)
+	s.Require().NoError(err)
+	gov := s.newGov(pubKeys, round, reset)
+	receivers, protocols := s.newProtocols(k, n, round, reset)
+	for _, receiver := range receivers {
+		gov.AddDKGMasterPublicKey(receiver.mpk)
+	}
+	for _, protocol := range protocols {
+		protocol.proposeMPKReady()
+	}
+	for _, recv := range receivers {
+		gov.AddDKGMPKReady(recv.ready[0])
+	}
+	for _, protocol := range protocols {
+		protocol.proposeFinalize()
+	}
+	for _, recv := range receivers {
+		gov.AddDKGFinalize(recv.final[0])
+	}
+	s.Require().True(gov.IsDKGFinal(round))
+	// Derived group public keys are stored.
+	store := &testTSigVerifierStore{
+		gpks: make(map[uint64]*typesDKG.GroupPublicKey),
+	}
+	cache := NewTSigVerifierCache(gov, 3)
+	cache.SetStore(store)
+	ok, err := cache.Update(round)
+	s.Require().NoError(err)
+	s.Require().True(ok)
+	s.Require().Equal(1, store.stored)
+	gpk, exist := cache.GroupPublicKey(round)
+	s.Require().True(exist)
+	// A restarted node loads them from the store, even if they can't be
+	// derived from governance.
+	cache = NewTSigVerifierCache(s.newGov(pubKeys, round, reset), 3)
+	cache.SetStore(store)
+	ok, err = cache.Update(round)
+	s.Require().NoError(err)
+	s.Require().True(ok)
+	s.Require().Equal(1, store.stored)
+	gpk2, exist := cache.GroupPublicKey(round)
+	s.Require().True(exist)
+	s.Require().Equal(gpk.Bytes(), gpk2.Bytes())
+	ok, err = cache.Update(round + 1)
+	s.Require().NoError(err)
+	s.Require().False(ok)
 }
 
 func (s *DKGTSIGProtocolTestSuite) TestUnexpectedDKGResetCount() {
@@ -1023,6 +1151,126 @@ func (s *DKGTSIGProtocolTestSuite) TestUnexpectedDKGResetCount() {
 	s.Require().IsType(ErrUnexpectedDKGResetCount{}, err)
 }
 
+func (s *DKGTSIGProtocolTestSuite) TestAntiComplaintFlooding() {
+	k := 2
+	n := 4
+	round := uint64(1)
+	reset := uint64(3)
+	_, pubKeys, err := test.NewKeys(5)
+	s.Require().NoError(err)
+	gov := s.newGov(pubKeys, round, reset)
+	receivers, protocols := s.newProtocols(k, n, round, reset)
+	for _, receiver := range receivers {
+		gov.AddDKGMasterPublicKey(receiver.mpk)
+	}
+	for _, protocol := range protocols {
+		s.Require().NoError(
+			protocol.processMasterPublicKeys(gov.DKGMasterPublicKeys(round)))
+	}
+	sourceID, targetID, thirdPerson := s.nIDs[0], s.nIDs[1], s.nIDs[2]
+	protocol := protocols[thirdPerson]
+	// Anti-complaints referencing non-participants are not tracked.
+	for i := 0; i < 100; i++ {
+		outsider := types.NodeID{Hash: common.NewRandomHash()}
+		receivers[sourceID].ProposeDKGPrivateShare(&typesDKG.PrivateShare{
+			ProposerID:   sourceID,
+			ReceiverID:   outsider,
+			Round:        round,
+			Reset:        reset,
+			PrivateShare: *dkg.NewPrivateKey(),
+		})
+		s.Require().NoError(protocol.processPrivateShare(
+			receivers[sourceID].prvShare[outsider]))
+		s.Require().Equal(ErrNotDKGParticipant,
+			protocol.processPrivateShare(&typesDKG.PrivateShare{
+				ProposerID: outsider,
+				ReceiverID: targetID,
+				Round:      round,
+				Reset:      reset,
+			}))
+	}
+	s.Require().Len(protocol.antiComplaintReceived, 0)
+	// Duplicated anti-complaints are tracked and forwarded only once.
+	antiComplaint := receivers[sourceID].prvShare[targetID]
+	for i := 0; i < 100; i++ {
+		s.Require().NoError(protocol.processPrivateShare(antiComplaint))
+	}
+	s.Require().Len(protocol.antiComplaintReceived, 1)
+	s.Require().Len(protocol.antiComplaintReceived[targetID], 1)
+	s.Require().Len(receivers[thirdPerson].antiComplaints, 1)
+	s.Require().Equal(1, protocol.stats.AntiComplaints)
+	// All per-round states are cleared after reset, and a new master public
+	// key is proposed.
+	oldMPK := receivers[thirdPerson].mpk
+	protocol.resetRoundState(round, reset, k)
+	s.Require().NotEqual(oldMPK, receivers[thirdPerson].mpk)
+	s.Require().Len(protocol.idMap, 0)
+	s.Require().Len(protocol.mpkMap, 0)
+	s.Require().Len(protocol.prvSharesReceived, 0)
+	s.Require().Len(protocol.nodeComplained, 0)
+	s.Require().Len(protocol.antiComplaintReceived, 0)
+	s.Require().Equal(0, protocol.step)
+	s.Require().Equal(&DKGStats{Round: round, Reset: reset}, protocol.stats)
+	mpks := []*typesDKG.MasterPublicKey{}
+	for _, receiver := range receivers {
+		mpks = append(mpks, receiver.mpk)
+	}
+	s.Require().NoError(protocol.processMasterPublicKeys(mpks))
+	s.Require().NoError(protocol.processPrivateShare(antiComplaint))
+	s.Require().Len(protocol.antiComplaintReceived[targetID], 1)
+}
+
+func (s *DKGTSIGProtocolTestSuite) TestEncryptedPrivateShare() {
+	k := 2
+	n := 4
+	round := uint64(1)
+	reset := uint64(3)
+	_, pubKeys, err := test.NewKeys(5)
+	s.Require().NoError(err)
+	gov := s.newGov(pubKeys, round, reset)
+	receivers, protocols := s.newProtocols(k, n, round, reset)
+	for _, receiver := range receivers {
+		gov.AddDKGMasterPublicKey(receiver.mpk)
+	}
+	for _, protocol := range protocols {
+		s.Require().NoError(
+			protocol.processMasterPublicKeys(gov.DKGMasterPublicKeys(round)))
+	}
+	sourceID, targetID, thirdPerson := s.nIDs[0], s.nIDs[1], s.nIDs[2]
+	cipher := testDKGShareCipher{}
+	prvShare := receivers[sourceID].prvShare[targetID]
+	encrypted, err := cipher.EncryptDKGShare(
+		nil, prvShare.PrivateShare.Bytes())
+	s.Require().NoError(err)
+	encryptedShare := &typesDKG.EncryptedPrivateShare{
+		ProposerID:     prvShare.ProposerID,
+		ReceiverID:     prvShare.ReceiverID,
+		Round:          prvShare.Round,
+		Reset:          prvShare.Reset,
+		EncryptedShare: encrypted,
+		Signature:      prvShare.Signature,
+	}
+	// Without a cipher, the encrypted share can't be processed.
+	s.Require().Equal(ErrUnableDecryptPrvShare,
+		protocols[targetID].processEncryptedPrivateShare(encryptedShare))
+	// Only the receiver is able to decrypt it.
+	protocols[thirdPerson].cipher = cipher
+	s.Require().Equal(ErrUnableDecryptPrvShare,
+		protocols[thirdPerson].processEncryptedPrivateShare(encryptedShare))
+	// The signature is verified after decryption.
+	protocols[targetID].cipher = cipher
+	tampered := *encryptedShare
+	tampered.Signature = receivers[sourceID].prvShare[thirdPerson].Signature
+	s.Require().Equal(ErrIncorrectPrivateShareSignature,
+		protocols[targetID].processEncryptedPrivateShare(&tampered))
+	s.Require().NoError(
+		protocols[targetID].processEncryptedPrivateShare(encryptedShare))
+	s.Require().Contains(protocols[targetID].prvSharesReceived, sourceID)
+	s.Require().Len(receivers[targetID].complaints, 0)
+	// The input should not be modified.
+	s.Require().Equal(encrypted, encryptedShare.EncryptedShare)
+}
+
 func TestDKGTSIGProtocol(t *testing.T) {
 	suite.Run(t, new(DKGTSIGProtocolTestSuite))
 }
@@ -29,7 +29,9 @@ import (
 // Application describes the application interface that interacts with DEXON
 // consensus core.
 type Application interface {
-	// PreparePayload is called when consensus core is preparing a block.
+	// PreparePayload is called when consensus core is preparing a block. The
+	// payload should not exceed MaxBlockPayloadSize in configuration of that
+	// round, or no block would be proposed.
 	PreparePayload(position types.Position) ([]byte, error)
 
 	// PrepareWitness will return the witness data no lower than consensusHeight.
@@ -89,6 +91,24 @@ type Network interface {
 	ReportBadPeerChan() chan<- interface{}
 }
 
+// DKGShareCipher encrypts DKG private shares sent directly to their receivers.
+// If the Network passed to Consensus also implements this interface, private
+// shares would be encrypted and sent via SendDKGEncryptedPrivateShare instead
+// of Network.SendDKGPrivateShare. Received typesDKG.EncryptedPrivateShare
+// should be delivered via Network.ReceiveChan like other messages.
+type DKGShareCipher interface {
+	// EncryptDKGShare encrypts a private share to the owner of pubKey.
+	EncryptDKGShare(pubKey crypto.PublicKey, share []byte) ([]byte, error)
+
+	// DecryptDKGShare decrypts a private share encrypted to this node.
+	DecryptDKGShare(encrypted []byte) ([]byte, error)
+
+	// SendDKGEncryptedPrivateShare sends an encrypted private share to its
+	// receiver.
+	SendDKGEncryptedPrivateShare(
+		pub crypto.PublicKey, share *typesDKG.EncryptedPrivateShare)
+}
+
 // Governance interface specifies interface to control the governance contract.
 // Note that there are a lot more methods in the governance contract, that this
 // interface only define those that are required to run the consensus algorithm.
@@ -160,6 +180,35 @@ type Governance interface {
 	DKGResetCount(round uint64) uint64
 }
 
+// GovernanceSubscriber is an optional interface of Governance.
+//
+// If the Governance passed to Consensus also implements this interface,
+// Consensus would invalidate its caches of a round once notified by
+// GovernanceEvent, instead of waiting for them to be refreshed lazily.
+type GovernanceSubscriber interface {
+	// Subscribe returns a channel of updates of governance data.
+	Subscribe() <-chan GovernanceEvent
+}
+
+// GovernanceEventType is the type of GovernanceEvent.
+type GovernanceEventType int
+
+// GovernanceEventType enum.
+const (
+	// GovernanceEventConfig means the configuration or node set of a round is
+	// updated.
+	GovernanceEventConfig GovernanceEventType = iota
+	// GovernanceEventCRS means the CRS of a round is proposed or reset.
+	GovernanceEventCRS
+)
+
+// GovernanceEvent notifies updates of governance data of a round, see
+// GovernanceSubscriber.
+type GovernanceEvent struct {
+	Type  GovernanceEventType
+	Round uint64
+}
+
 // Ticker define the capability to tick by interval.
 type Ticker interface {
 	// Tick would return a channel, which would be triggered until next tick.
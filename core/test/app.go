@@ -73,6 +73,17 @@ var (
 	ErrInvalidHeight = fmt.Errorf("invalid height")
 )
 
+// VerifiableApplication is the interface of applications an App could attach
+// to, it's identical to core.Application. core.Application can't be referred
+// here, or an import cycle would be introduced in tests of core package.
+type VerifiableApplication interface {
+	PreparePayload(position types.Position) ([]byte, error)
+	PrepareWitness(consensusHeight uint64) (types.Witness, error)
+	VerifyBlock(block *types.Block) types.BlockVerifyStatus
+	BlockConfirmed(block types.Block)
+	BlockDelivered(blockHash common.Hash, position types.Position, rand []byte)
+}
+
 // AppDeliveredRecord caches information when this application received
 // a block delivered notification.
 type AppDeliveredRecord struct {
@@ -94,6 +105,28 @@ type App struct {
 	rEvt                *utils.RoundEvent
 	hEvt                *common.Event
 	roundToNotify       uint64
+	verifyOnly          bool
+	violation           error
+	violationLock       sync.RWMutex
+}
+
+// attachedApp forwards calls to the target application and mirrors blocks
+// confirmed and delivered to the verifier.
+type attachedApp struct {
+	VerifiableApplication
+
+	verifier *App
+}
+
+func (a *attachedApp) BlockConfirmed(b types.Block) {
+	a.verifier.BlockConfirmed(*b.Clone())
+	a.VerifiableApplication.BlockConfirmed(b)
+}
+
+func (a *attachedApp) BlockDelivered(
+	blockHash common.Hash, pos types.Position, rand []byte) {
+	a.verifier.BlockDelivered(blockHash, pos, rand)
+	a.VerifiableApplication.BlockDelivered(blockHash, pos, rand)
 }
 
 // NewApp constructs a TestApp instance.
@@ -123,6 +156,39 @@ func NewApp(initRound uint64, gov *Governance, rEvt *utils.RoundEvent) (
 	return app
 }
 
+// AttachTo switches this App to verify-only mode and returns an application
+// wrapping 'target', which should be passed to core.Consensus instead of
+// 'target'. Blocks confirmed and delivered by that Consensus instance are
+// verified incrementally, and the first violation found is reported by
+// Violation method instead of panic.
+func (app *App) AttachTo(target VerifiableApplication) VerifiableApplication {
+	app.verifyOnly = true
+	return &attachedApp{
+		VerifiableApplication: target,
+		verifier:              app,
+	}
+}
+
+// Violation returns the first violation found in verify-only mode.
+func (app *App) Violation() error {
+	app.violationLock.RLock()
+	defer app.violationLock.RUnlock()
+	return app.violation
+}
+
+// reportViolation panics with the error, or records it when in verify-only
+// mode.
+func (app *App) reportViolation(err error) {
+	if !app.verifyOnly {
+		panic(err)
+	}
+	app.violationLock.Lock()
+	defer app.violationLock.Unlock()
+	if app.violation == nil {
+		app.violation = err
+	}
+}
+
 // PreparePayload implements Application interface.
 func (app *App) PreparePayload(position types.Position) ([]byte, error) {
 	if app.state == nil {
@@ -191,7 +257,7 @@ func (app *App) BlockConfirmed(b types.Block) {
 	defer app.confirmedLock.Unlock()
 	app.Confirmed[b.Hash] = &b
 	if app.LastConfirmedHeight+1 != b.Position.Height {
-		panic(ErrConfirmedHeightNotIncreasing)
+		app.reportViolation(ErrConfirmedHeightNotIncreasing)
 	}
 	app.LastConfirmedHeight = b.Position.Height
 }
@@ -208,6 +274,11 @@ func (app *App) ClearUndeliveredBlocks() {
 // BlockDelivered implements Application interface.
 func (app *App) BlockDelivered(blockHash common.Hash, pos types.Position,
 	rand []byte) {
+	if app.verifyOnly {
+		if err := app.verifyDelivered(blockHash, pos, rand); err != nil {
+			app.reportViolation(err)
+		}
+	}
 	func() {
 		app.deliveredLock.Lock()
 		defer app.deliveredLock.Unlock()
@@ -221,10 +292,9 @@ func (app *App) BlockDelivered(blockHash common.Hash, pos types.Position,
 			lastHash := app.DeliverSequence[len(app.DeliverSequence)-1]
 			d, exists := app.Delivered[lastHash]
 			if !exists {
-				panic(ErrParentBlockNotDelivered)
-			}
-			if d.Pos.Height+1 != pos.Height {
-				panic(ErrHeightOutOfOrder)
+				app.reportViolation(ErrParentBlockNotDelivered)
+			} else if d.Pos.Height+1 != pos.Height {
+				app.reportViolation(ErrHeightOutOfOrder)
 			}
 		}
 		app.DeliverSequence = append(app.DeliverSequence, blockHash)
@@ -238,10 +308,12 @@ func (app *App) BlockDelivered(blockHash common.Hash, pos types.Position,
 		defer app.confirmedLock.RUnlock()
 		b, exists := app.Confirmed[blockHash]
 		if !exists {
-			panic(ErrDeliveredBlockNotConfirmed)
+			app.reportViolation(ErrDeliveredBlockNotConfirmed)
+			return
 		}
 		if !b.Position.Equal(pos) {
-			panic(ErrMismatchDeliverPosition)
+			app.reportViolation(ErrMismatchDeliverPosition)
+			return
 		}
 		if err := app.state.Apply(b.Payload); err != nil {
 			if err != ErrDuplicatedChange {
@@ -258,6 +330,41 @@ func (app *App) BlockDelivered(blockHash common.Hash, pos types.Position,
 	app.hEvt.NotifyHeight(pos.Height)
 }
 
+// verifyDelivered performs checks in Verify method against one delivered block,
+// based on blocks delivered before it.
+func (app *App) verifyDelivered(
+	blockHash common.Hash, pos types.Position, rand []byte) error {
+	app.confirmedLock.RLock()
+	defer app.confirmedLock.RUnlock()
+	app.deliveredLock.RLock()
+	defer app.deliveredLock.RUnlock()
+	b, exists := app.Confirmed[blockHash]
+	if !exists {
+		return ErrDeliveredBlockNotConfirmed
+	}
+	if !b.Position.Equal(pos) {
+		return ErrMismatchDeliverPosition
+	}
+	if len(rand) == 0 {
+		return ErrEmptyRandomness
+	}
+	if pos.Height < types.GenesisHeight {
+		return ErrInvalidHeight
+	}
+	lastHash, lastRec := app.LastDeliveredRecordNoLock()
+	if lastRec == nil {
+		return nil
+	}
+	if lastRec.Pos.Height+1 != pos.Height {
+		return ErrHeightOutOfOrder
+	}
+	if last, exists := app.Confirmed[lastHash]; exists &&
+		last.Timestamp.After(b.Timestamp) {
+		return ErrTimestampOutOfOrder
+	}
+	return nil
+}
+
 // GetLatestDeliveredPosition would return the latest position of delivered
 // block seen by this application instance.
 func (app *App) GetLatestDeliveredPosition() types.Position {
@@ -197,6 +197,43 @@ func (s *AppTestSuite) TestVerify() {
 	s.Require().NoError(app.Verify())
 }
 
+func (s *AppTestSuite) TestAttachTo() {
+	var (
+		req = s.Require()
+		now = time.Now().UTC()
+		b0  = types.Block{
+			Hash:       common.NewRandomHash(),
+			Position:   types.Position{Height: types.GenesisHeight},
+			Randomness: []byte("b0"),
+			Timestamp:  now,
+		}
+		b1 = types.Block{
+			Hash:       common.NewRandomHash(),
+			Position:   types.Position{Height: types.GenesisHeight + 1},
+			Randomness: []byte("b1"),
+			Timestamp:  now.Add(-1 * time.Second),
+		}
+		target   = NewApp(0, nil, nil)
+		verifier = NewApp(0, nil, nil)
+		app      = verifier.AttachTo(target)
+	)
+	app.BlockConfirmed(b0)
+	app.BlockDelivered(b0.Hash, b0.Position, b0.Randomness)
+	req.NoError(verifier.Violation())
+	// Blocks should be forwarded to the target application.
+	req.Equal(common.Hashes{b0.Hash}, target.DeliverSequence)
+	// A violation should be recorded instead of panic.
+	app.BlockConfirmed(b1)
+	app.BlockDelivered(b1.Hash, b1.Position, b1.Randomness)
+	req.Equal(ErrTimestampOutOfOrder, verifier.Violation())
+	req.Equal(common.Hashes{b0.Hash, b1.Hash}, target.DeliverSequence)
+	// Only the first violation is kept.
+	req.NotPanics(func() {
+		verifier.BlockDelivered(common.NewRandomHash(), b1.Position, nil)
+	})
+	req.Equal(ErrTimestampOutOfOrder, verifier.Violation())
+}
+
 func (s *AppTestSuite) TestWitness() {
 	// Deliver several blocks, there is only one chain only.
 	app := NewApp(0, nil, nil)
@@ -18,7 +18,9 @@
 package test
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/dexon-foundation/dexon-consensus/core/crypto"
@@ -45,6 +47,10 @@ type FakeTransport struct {
 	serverChannel chan<- *TransportEnvelope
 	peers         map[types.NodeID]fakePeerRecord
 	dMoment       time.Time
+	// maxMessageAge is the maximum simulated latency of a broadcasted
+	// message, messages delayed longer than it are dropped.
+	maxMessageAge time.Duration
+	droppedStale  uint64
 }
 
 // NewFakeTransportServer constructs FakeTransport instance for peer server.
@@ -107,8 +113,12 @@ func (t *FakeTransport) Broadcast(endpoints map[types.NodeID]struct{},
 		if ID == t.nID {
 			continue
 		}
+		delay := latency.Delay()
+		if t.isStale(delay) {
+			continue
+		}
 		go func(nID types.NodeID) {
-			time.Sleep(latency.Delay())
+			time.Sleep(delay)
 			// #nosec G104
 			t.Send(nID, msg)
 		}(ID)
@@ -116,6 +126,29 @@ func (t *FakeTransport) Broadcast(endpoints map[types.NodeID]struct{},
 	return
 }
 
+// SetMaxMessageAge drops broadcasted messages whose simulated latency is
+// longer than age, they are considered irrelevant when delivered. There is no
+// limit when age is zero. It should be called before Join.
+func (t *FakeTransport) SetMaxMessageAge(age time.Duration) {
+	t.maxMessageAge = age
+}
+
+// DroppedStaleMsgs returns the count of messages dropped due to the maximum
+// message age.
+func (t *FakeTransport) DroppedStaleMsgs() uint64 {
+	return atomic.LoadUint64(&t.droppedStale)
+}
+
+// isStale checks if a message delayed that long should be dropped, it's
+// counted when dropped.
+func (t *FakeTransport) isStale(delay time.Duration) bool {
+	if t.maxMessageAge == 0 || delay <= t.maxMessageAge {
+		return false
+	}
+	atomic.AddUint64(&t.droppedStale, 1)
+	return true
+}
+
 // Close implements Transport.Close method.
 func (t *FakeTransport) Close() (err error) {
 	close(t.recvChannel)
@@ -131,7 +164,7 @@ func (t *FakeTransport) Peers() (peers []crypto.PublicKey) {
 }
 
 // Join implements TransportClient.Join method.
-func (t *FakeTransport) Join(
+func (t *FakeTransport) Join(ctx context.Context,
 	serverEndpoint interface{}) (<-chan *TransportEnvelope, error) {
 
 	var (
@@ -146,7 +179,12 @@ func (t *FakeTransport) Join(
 	}
 	// Wait for peers info.
 	for {
-		envelope := <-t.recvChannel
+		var envelope *TransportEnvelope
+		select {
+		case envelope = <-t.recvChannel:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
 		if envelope.PeerType != TransportPeerServer {
 			envelopes = append(envelopes, envelope)
 			continue
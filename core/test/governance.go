@@ -45,6 +45,7 @@ type Governance struct {
 	networkModule        *Network
 	pendingConfigChanges map[uint64]map[StateChangeType]interface{}
 	prohibitedTypes      map[StateChangeType]struct{}
+	crsSeed              []byte
 	lock                 sync.RWMutex
 }
 
@@ -110,7 +111,16 @@ func (g *Governance) GetRoundHeight(round uint64) uint64 {
 
 // CRS returns the CRS for a given round.
 func (g *Governance) CRS(round uint64) common.Hash {
-	return g.stateModule.CRS(round)
+	crs := g.stateModule.CRS(round)
+	if (crs == common.Hash{}) {
+		return crs
+	}
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	if g.crsSeed != nil {
+		return DeterministicCRS(g.crsSeed, round)
+	}
+	return crs
 }
 
 // NotifyRound notifies governace contract to snapshot config, and broadcast
@@ -375,6 +385,15 @@ func (g *Governance) CatchUpWithRound(round uint64) {
 	}
 }
 
+// UseDeterministicCRS makes CRS derived from the seed by DeterministicCRS. The
+// time when CRS of a round is ready is still decided by the underlying State
+// instance, only the value is replaced.
+func (g *Governance) UseDeterministicCRS(seed []byte) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.crsSeed = common.CopyBytes(seed)
+}
+
 // Clone a governance instance with replicate internal state.
 func (g *Governance) Clone() *Governance {
 	g.lock.RLock()
@@ -421,6 +440,7 @@ func (g *Governance) Clone() *Governance {
 		nodeSets:             copiedNodeSets,
 		pendingConfigChanges: copiedPendingChanges,
 		prohibitedTypes:      copiedProhibitedTypes,
+		crsSeed:              common.CopyBytes(g.crsSeed),
 	}
 }
 
@@ -482,8 +502,15 @@ func (g *Governance) Equal(other *Governance, checkState bool) bool {
 // NOTE: this function should be called before running.
 func (g *Governance) RegisterConfigChange(
 	round uint64, t StateChangeType, v interface{}) (err error) {
-	if t < StateAddCRS || t > StateChangeNotarySetSize {
-		return fmt.Errorf("state changes to register is not supported: %v", t)
+	switch t {
+	case StateChangeMaxClockSkew, StateChangeMaxBlockPayloadSize:
+	default:
+		// StateAddNode is placed between configuration related types, but
+		// it's not a configuration change.
+		if t < StateAddCRS || t > StateChangeNotarySetSize {
+			return fmt.Errorf(
+				"state changes to register is not supported: %v", t)
+		}
 	}
 	if round < 2 {
 		return errors.New(
@@ -102,6 +102,10 @@ func (s *GovernanceTestSuite) TestRegisterChange() {
 	req.NoError(g.RegisterConfigChange(5, StateChangeNotarySetSize, uint32(32)))
 	req.NoError(g.RegisterConfigChange(6, StateChangeNotarySetSize, uint32(32)))
 	req.NoError(g.RegisterConfigChange(7, StateChangeNotarySetSize, uint32(40)))
+	req.NoError(g.RegisterConfigChange(
+		7, StateChangeMaxBlockPayloadSize, uint64(1024)))
+	// Node set changes are not configuration changes.
+	req.Error(g.RegisterConfigChange(7, StateAddNode, genesisNodes[0]))
 	// In local mode, state for round 6 would be ready after notified with
 	// round 2.
 	g.NotifyRound(2, roundLength*2)
@@ -183,6 +187,34 @@ func (s *GovernanceTestSuite) TestProhibit() {
 	s.Require().True(gov.IsDKGFinal(round))
 }
 
+func (s *GovernanceTestSuite) TestDeterministicCRS() {
+	var (
+		req  = s.Require()
+		seed = []byte("deterministic")
+	)
+	req.Equal(DeterministicCRS(seed, 1), DeterministicCRS(seed, 1))
+	req.NotEqual(DeterministicCRS(seed, 1), DeterministicCRS(seed, 2))
+	req.NotEqual(DeterministicCRS(seed, 1), DeterministicCRS([]byte("x"), 1))
+	_, genesisNodes, err := NewKeys(4)
+	req.NoError(err)
+	newGov := func() *Governance {
+		g, err := NewGovernance(NewState(
+			1, genesisNodes, 100*time.Millisecond, &common.NullLogger{}, true), 2)
+		req.NoError(err)
+		g.UseDeterministicCRS(seed)
+		return g
+	}
+	g1, g2 := newGov(), newGov()
+	for round := uint64(0); round <= 1; round++ {
+		req.Equal(DeterministicCRS(seed, round), g1.CRS(round))
+		req.Equal(g1.CRS(round), g2.CRS(round))
+	}
+	// CRS not proposed yet should not be ready.
+	req.Equal(common.Hash{}, g1.CRS(2))
+	// Clone should keep the seed.
+	req.Equal(g1.CRS(1), g1.Clone().CRS(1))
+}
+
 func TestGovernance(t *testing.T) {
 	suite.Run(t, new(GovernanceTestSuite))
 }
@@ -18,6 +18,7 @@
 package test
 
 import (
+	"context"
 	"time"
 
 	"github.com/dexon-foundation/dexon-consensus/core/crypto"
@@ -73,8 +74,11 @@ type TransportClient interface {
 	Transport
 	// Report a message to the peer server.
 	Report(msg interface{}) error
-	// Join the network, should block until joined.
-	Join(serverEndpoint interface{}) (<-chan *TransportEnvelope, error)
+	// Join the network, should block until joined or ctx is done. Resources
+	// allocated by Join are only released by Close, which should not be
+	// called before Join returns.
+	Join(ctx context.Context, serverEndpoint interface{}) (
+		<-chan *TransportEnvelope, error)
 
 	// DMoment returns the DMoment of the network.
 	DMoment() time.Time
@@ -19,6 +19,7 @@ package test
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -32,11 +33,35 @@ type LatencyModel interface {
 type NormalLatencyModel struct {
 	Sigma float64
 	Mean  float64
+
+	// When rand is nil, the global source from math/rand is used.
+	rand     *rand.Rand
+	randLock sync.Mutex
+}
+
+// NewNormalLatencyModel constructs a NormalLatencyModel instance drawing
+// latencies from its own random source, the same seed always leads to the
+// same sequence of latencies.
+func NewNormalLatencyModel(
+	mean, sigma float64, seed int64) *NormalLatencyModel {
+	return &NormalLatencyModel{
+		Mean:  mean,
+		Sigma: sigma,
+		rand:  rand.New(rand.NewSource(seed)),
+	}
 }
 
 // Delay implements LatencyModel interface.
 func (m *NormalLatencyModel) Delay() time.Duration {
-	delay := rand.NormFloat64()*m.Sigma + m.Mean
+	var delay float64
+	if m.rand == nil {
+		delay = rand.NormFloat64()*m.Sigma + m.Mean
+	} else {
+		// rand.Rand is not safe for concurrent use.
+		m.randLock.Lock()
+		delay = m.rand.NormFloat64()*m.Sigma + m.Mean
+		m.randLock.Unlock()
+	}
 	if delay < 0 {
 		delay = m.Sigma / 2
 	}
@@ -20,11 +20,21 @@ package test
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 
+	"github.com/dexon-foundation/dexon-consensus/common"
 	"github.com/dexon-foundation/dexon-consensus/core/types"
 	typesDKG "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
 )
 
+// rawReceiptRequest is the serialized form of receiptRequest, the wrapped
+// message is marshalled by the same marshaller.
+type rawReceiptRequest struct {
+	ID      common.Hash `json:"id"`
+	Type    string      `json:"type"`
+	Payload []byte      `json:"payload"`
+}
+
 // DefaultMarshaller is the default marshaller for testing core.Consensus.
 type DefaultMarshaller struct {
 	fallback Marshaller
@@ -101,6 +111,34 @@ func (m *DefaultMarshaller) Unmarshal(
 			break
 		}
 		msg = req
+	case "block-chunk":
+		chunk := &blockChunk{}
+		if err = json.Unmarshal(payload, chunk); err != nil {
+			break
+		}
+		msg = chunk
+	case "app-message":
+		appMsg := &appMessage{}
+		if err = json.Unmarshal(payload, appMsg); err != nil {
+			break
+		}
+		msg = appMsg
+	case "receipt-request":
+		rawReq := &rawReceiptRequest{}
+		if err = json.Unmarshal(payload, rawReq); err != nil {
+			break
+		}
+		req := &receiptRequest{ID: rawReq.ID}
+		if req.Msg, err = m.Unmarshal(rawReq.Type, rawReq.Payload); err != nil {
+			break
+		}
+		msg = req
+	case "receipt":
+		r := &receipt{}
+		if err = json.Unmarshal(payload, r); err != nil {
+			break
+		}
+		msg = r
 	default:
 		if m.fallback == nil {
 			err = fmt.Errorf("unknown msg type: %v", msgType)
@@ -145,6 +183,23 @@ func (m *DefaultMarshaller) Marshal(
 	case *PullRequest:
 		msgType = "pull-request"
 		payload, err = json.Marshal(msg)
+	case *blockChunk:
+		msgType = "block-chunk"
+		payload, err = json.Marshal(msg)
+	case *appMessage:
+		msgType = "app-message"
+		payload, err = json.Marshal(msg)
+	case *receiptRequest:
+		req := msg.(*receiptRequest)
+		rawReq := &rawReceiptRequest{ID: req.ID}
+		if rawReq.Type, rawReq.Payload, err = m.Marshal(req.Msg); err != nil {
+			break
+		}
+		msgType = "receipt-request"
+		payload, err = json.Marshal(rawReq)
+	case *receipt:
+		msgType = "receipt"
+		payload, err = json.Marshal(msg)
 	default:
 		if m.fallback == nil {
 			err = fmt.Errorf("unknwon message type: %v", msg)
@@ -154,3 +209,64 @@ func (m *DefaultMarshaller) Marshal(
 	}
 	return
 }
+
+// TypedMarshaller dispatches messages to marshallers registered for their
+// concrete types, messages of other types are handled by the fallback
+// marshaller. Registered marshallers should unmarshal messages to the same
+// concrete types, or Network would not recognize them.
+type TypedMarshaller struct {
+	fallback  Marshaller
+	byType    map[reflect.Type]Marshaller
+	byMsgType map[string]Marshaller
+}
+
+// NewTypedMarshaller constructs an TypedMarshaller instance.
+func NewTypedMarshaller(fallback Marshaller) *TypedMarshaller {
+	return &TypedMarshaller{
+		fallback:  fallback,
+		byType:    make(map[reflect.Type]Marshaller),
+		byMsgType: make(map[string]Marshaller),
+	}
+}
+
+// Register a marshaller for messages of the same concrete type as 'msg',
+// 'msgType' is the type returned by that marshaller when marshalling those
+// messages. It should be called before passing to Network.
+func (m *TypedMarshaller) Register(
+	msg interface{}, msgType string, marshaller Marshaller) {
+	m.byType[reflect.TypeOf(msg)] = marshaller
+	m.byMsgType[msgType] = marshaller
+}
+
+// Unmarshal implements Marshaller interface.
+func (m *TypedMarshaller) Unmarshal(
+	msgType string, payload []byte) (msg interface{}, err error) {
+	if marshaller, exists := m.byMsgType[msgType]; exists {
+		return marshaller.Unmarshal(msgType, payload)
+	}
+	if m.fallback == nil {
+		err = fmt.Errorf("unrecognized message type: %v", msgType)
+		return
+	}
+	return m.fallback.Unmarshal(msgType, payload)
+}
+
+// Marshal implements Marshaller interface.
+func (m *TypedMarshaller) Marshal(
+	msg interface{}) (msgType string, payload []byte, err error) {
+	marshaller, exists := m.byType[reflect.TypeOf(msg)]
+	if !exists {
+		if m.fallback == nil {
+			err = fmt.Errorf("unknwon message type: %v", msg)
+			return
+		}
+		return m.fallback.Marshal(msg)
+	}
+	if msgType, payload, err = marshaller.Marshal(msg); err != nil {
+		return
+	}
+	if m.byMsgType[msgType] != marshaller {
+		err = fmt.Errorf("unregistered message type: %v", msgType)
+	}
+	return
+}
@@ -22,9 +22,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"math/rand"
 	"net"
+	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
@@ -40,9 +44,69 @@ const (
 	maxBlockCache       = 1000
 	maxVoteCache        = 128
 
+	// Default count of positions whose votes are cached.
+	defaultVotePositionWindow = maxVoteCache
+
+	// Version of the format of exported vote cache.
+	voteCacheSnapshotVersion = 1
+
+	// Default count of pulling routines running at the same time.
+	defaultMaxConcurrentPulls = 16
+
+	// Parameters for dispatching received messages.
+	defaultDispatchWorkers = 16
+	dispatchQueueSize      = 1000
+
 	// Gossiping parameter.
 	maxAgreementResultBroadcast  = 3
 	gossipAgreementResultPercent = 33
+
+	// Default count of distinct agreement results gossiped for one position
+	// within agreementResultRateWindow.
+	defaultMaxAgreementResultsPerPosition = 2
+	agreementResultRateWindow             = 10 * time.Second
+
+	// Parameters for serving pull requests.
+	defaultMaxBlocksPerPull       = 128
+	defaultMaxPullRequestsPerPeer = 100
+	defaultPullRequestRateWindow  = 1 * time.Second
+
+	// Parameters for markers of sent agreement results.
+	maxSentAgreementCache   = 1000
+	defaultSentMarkerTTL    = 1 * time.Minute
+	sentMarkerSweepInterval = 5 * time.Second
+
+	// Parameters for block chunking.
+	maxBlockChunkCount = 1024
+	blockChunkTimeout  = 10 * time.Second
+
+	// Parameters for scoring peers by their responsiveness to pull requests.
+	peerScoreReward         = 1.0
+	peerScorePenalty        = 1.0
+	peerScoreHalfLife       = 1 * time.Minute
+	peerScoreExplorePercent = 10
+
+	// Parameters for sending messages with acknowledgement.
+	defaultMaxSendRetries       = 3
+	reliablePrivateShareTimeout = 1 * time.Second
+)
+
+var (
+	// ErrNotEnoughConfirmation means the count of peers confirming receipt of a
+	// message is less than required.
+	ErrNotEnoughConfirmation = errors.New("not enough confirmation")
+	// ErrSendNotConfirmed means the receiver doesn't confirm receipt of a
+	// message after all retries.
+	ErrSendNotConfirmed = errors.New("send not confirmed")
+	// ErrUnhandledMessage means a received message is not handled by
+	// network module.
+	ErrUnhandledMessage = errors.New("unhandled message")
+	// ErrJoinTimeout means the transport layer is unable to join the peer
+	// server in NetworkConfig.JoinTimeout.
+	ErrJoinTimeout = errors.New("join timeout")
+	// ErrVoteCacheVersionMismatch means the exported vote cache to import is
+	// in a format of other version.
+	ErrVoteCacheVersionMismatch = errors.New("vote cache version mismatch")
 )
 
 // NetworkType is the simulation network type.
@@ -55,6 +119,22 @@ const (
 	NetworkTypeFake     NetworkType = "fake"
 )
 
+// UnhandledMsgPolicy determines how to handle received messages not handled
+// by network module.
+type UnhandledMsgPolicy string
+
+// UnhandledMsgPolicy enums.
+const (
+	// UnhandledMsgForward forwards them to the channel returned by
+	// ReceiveChanForNode.
+	UnhandledMsgForward UnhandledMsgPolicy = "forward"
+	// UnhandledMsgDrop drops them.
+	UnhandledMsgDrop UnhandledMsgPolicy = "drop"
+	// UnhandledMsgReport drops them and reports errors to the channel
+	// returned by UnhandledMsgErrChan.
+	UnhandledMsgReport UnhandledMsgPolicy = "report"
+)
+
 // NetworkConfig is the configuration for Network module.
 type NetworkConfig struct {
 	Type          NetworkType
@@ -63,8 +143,181 @@ type NetworkConfig struct {
 	DirectLatency LatencyModel
 	GossipLatency LatencyModel
 	Marshaller    Marshaller
+	// MaxConcurrentPulls limits the count of pulling routines running at the
+	// same time, requests exceeding this limit would be queued and coalesced.
+	// defaultMaxConcurrentPulls is used when it's zero.
+	MaxConcurrentPulls int
+	// MaxAgreementResultsPerPosition limits the count of distinct agreement
+	// results gossiped for one position within a time window, excess ones
+	// would be dropped. defaultMaxAgreementResultsPerPosition is used when
+	// it's zero.
+	MaxAgreementResultsPerPosition int
+	// BlockChunkSize is the size of payload in one chunk when sending a block
+	// with payload larger than it, chunking is disabled when it's zero or the
+	// network type is NetworkTypeFake.
+	BlockChunkSize int
+	// SentMarkerTTL is how long a marker of sent agreement result would be
+	// kept to avoid gossiping it again. defaultSentMarkerTTL is used when it's
+	// zero.
+	SentMarkerTTL time.Duration
+	// DisableGossip skips gossiping blocks and agreement results to peers not
+	// in notary set, missing ones could still be pulled. It's only safe when
+	// notary set covers all peers.
+	DisableGossip bool
+	// MaxSendRetries is the count of retries of SendReliable when the receipt
+	// is not confirmed in time. defaultMaxSendRetries is used when it's zero.
+	MaxSendRetries int
+	// ReliablePrivateShare makes SendDKGPrivateShare wait for receipts of
+	// private shares and resend them when not confirmed.
+	ReliablePrivateShare bool
+	// VotePositionWindow is the maximum count of positions whose votes are
+	// cached for pulling. defaultVotePositionWindow is used when it's zero.
+	VotePositionWindow int
+	// DispatchWorkers is the count of routines dispatching received messages,
+	// messages received when all of them are busy and the queue is full are
+	// dropped. defaultDispatchWorkers is used when it's zero.
+	DispatchWorkers int
+	// UnhandledMsgPolicy determines how to handle messages not handled by
+	// network module, UnhandledMsgForward is used when it's empty.
+	UnhandledMsgPolicy UnhandledMsgPolicy
+	// MaxBlocksPerPull limits the count of blocks served for one pull request,
+	// the rest are ignored. Pulls of more blocks are split, and the rest are
+	// queued to be pulled later. defaultMaxBlocksPerPull is used when it's
+	// zero.
+	MaxBlocksPerPull int
+	// MaxPullRequestsPerPeer limits the count of pull requests served for one
+	// peer within PullRequestRateWindow, excess ones would be dropped.
+	// defaultMaxPullRequestsPerPeer is used when it's zero.
+	MaxPullRequestsPerPeer int
+	// PullRequestRateWindow is the period to count pull requests from one
+	// peer. defaultPullRequestRateWindow is used when it's zero.
+	PullRequestRateWindow time.Duration
+	// MaxMessageAge is the maximum simulated latency of messages sent via
+	// NetworkTypeFake, messages delayed longer than it are dropped since
+	// they might be irrelevant when delivered. There is no limit when it's
+	// zero, and it's ignored by other network types.
+	MaxMessageAge time.Duration
+	// JoinTimeout limits the time to join the peer server in Setup, the
+	// transport layer would be closed when timeout. There is no limit when
+	// it's zero.
+	JoinTimeout time.Duration
+	// AgreementResultRoundWindow limits rounds of received agreement results
+	// to be within the window of the round set by SetCurrentRound, others
+	// are dropped before reaching consensus core. There is no limit when it's
+	// zero, or before SetCurrentRound is called.
+	AgreementResultRoundWindow uint64
+}
+
+// NetworkStats is a snapshot of statistics collected by Network module.
+type NetworkStats struct {
+	// ActivePulls is the count of pulling routines running.
+	ActivePulls int
+	// QueuedPulls is the count of block hashes and vote positions waiting for
+	// a free pulling routine.
+	QueuedPulls int
+	// DroppedAgreementResults is the count of agreement results not gossiped
+	// due to per-position rate limiting.
+	DroppedAgreementResults uint64
+	// PeerScores is the decayed score of each peer scored by pull responses.
+	PeerScores map[types.NodeID]float64
+	// DroppedUnreachableMsgs is the count of messages not sent to peers
+	// marked as unreachable.
+	DroppedUnreachableMsgs uint64
+	// ThrottledPullRequests is the count of pull requests dropped due to
+	// per-peer rate limiting.
+	ThrottledPullRequests uint64
+	// DroppedUnhandledMsgs is the count of messages dropped due to
+	// UnhandledMsgPolicy.
+	DroppedUnhandledMsgs uint64
+	// RemarkedAgreementResults is the count of agreement results gossiped
+	// again after their sent markers are evicted, a growing value implies
+	// markers are evicted too early and might cause gossip loops.
+	RemarkedAgreementResults uint64
+	// DroppedStaleMsgs is the count of messages dropped due to
+	// NetworkConfig.MaxMessageAge.
+	DroppedStaleMsgs uint64
+	// ImplausibleAgreementResults is the count of received agreement results
+	// dropped due to NetworkConfig.AgreementResultRoundWindow.
+	ImplausibleAgreementResults uint64
+	// DroppedBusyMsgs is the count of received messages dropped because all
+	// dispatching routines are busy.
+	DroppedBusyMsgs uint64
+	// DroppedAppMsgs is the count of app messages dropped because the
+	// channel returned by AppMessageChan is full.
+	DroppedAppMsgs uint64
+}
+
+// receiptRequest wraps a message whose receiver should reply a receipt.
+type receiptRequest struct {
+	ID  common.Hash
+	Msg interface{}
+}
+
+// receipt confirms a message wrapped in receiptRequest is received.
+type receipt struct {
+	ID common.Hash `json:"id"`
 }
 
+// appMessage wraps a message between applications, which is routed to the
+// channel returned by AppMessageChan.
+type appMessage struct {
+	Payload []byte `json:"payload"`
+}
+
+// voteCacheSnapshot is the format of vote cache exported by ExportVoteCache,
+// votes are sorted by the order of their positions being cached.
+type voteCacheSnapshot struct {
+	Version int           `json:"version"`
+	Votes   []*types.Vote `json:"votes"`
+}
+
+// blockChunk is a piece of payload of a block too large to be sent in one
+// message, the first chunk also carries the block without payload.
+type blockChunk struct {
+	BlockHash common.Hash  `json:"hash"`
+	Index     int          `json:"index"`
+	Total     int          `json:"total"`
+	Block     *types.Block `json:"block,omitempty"`
+	Data      []byte       `json:"data"`
+}
+
+// pendingBlockChunks collects chunks of a block under reassembling.
+type pendingBlockChunks struct {
+	block    *types.Block
+	data     [][]byte
+	received int
+	since    time.Time
+}
+
+// peerScore is the score of a peer about how responsive it is to pull
+// requests, it decays toward zero over time.
+type peerScore struct {
+	value   float64
+	updated time.Time
+}
+
+func (s *peerScore) decayed(now time.Time) float64 {
+	return s.value * math.Pow(
+		0.5, float64(now.Sub(s.updated))/float64(peerScoreHalfLife))
+}
+
+// agreementResultRate records distinct agreement results gossiped for one
+// position since a moment.
+type agreementResultRate struct {
+	since time.Time
+	count int
+}
+
+// pullRequestRate records pull requests served for one peer since a moment.
+type pullRequestRate struct {
+	since time.Time
+	count int
+}
+
+// pullRequestVersion is the version of the wire format of PullRequest.
+// Pull requests without version are treated as version 1.
+const pullRequestVersion = 1
+
 // PullRequest is a generic request to pull everything (ex. vote, block...).
 type PullRequest struct {
 	Requester types.NodeID
@@ -72,13 +325,15 @@ type PullRequest struct {
 	Identity  interface{}
 }
 
-// MarshalJSON implements json.Marshaller.
+// MarshalJSON implements json.Marshaller. Hashes of blocks are sorted, thus
+// pull requests of the same set of blocks are marshalled identically.
 func (req *PullRequest) MarshalJSON() (b []byte, err error) {
 	var idAsBytes []byte
 	// Make sure caller prepare correct identity for pull requests.
 	switch req.Type {
 	case "block":
-		idAsBytes, err = json.Marshal(req.Identity.(common.Hashes))
+		hashes := append(common.Hashes(nil), req.Identity.(common.Hashes)...)
+		idAsBytes, err = json.Marshal(common.NewSortedHashes(hashes))
 	case "vote":
 		idAsBytes, err = json.Marshal(req.Identity.(types.Position))
 	default:
@@ -88,16 +343,18 @@ func (req *PullRequest) MarshalJSON() (b []byte, err error) {
 		return
 	}
 	b, err = json.Marshal(&struct {
+		Version   uint32       `json:"v"`
 		Requester types.NodeID `json:"req"`
 		Type      string       `json:"type"`
 		Identity  []byte       `json:"id"`
-	}{req.Requester, req.Type, idAsBytes})
+	}{pullRequestVersion, req.Requester, req.Type, idAsBytes})
 	return
 }
 
 // UnmarshalJSON iumplements json.Unmarshaller.
 func (req *PullRequest) UnmarshalJSON(data []byte) (err error) {
 	rawReq := &struct {
+		Version   uint32       `json:"v"`
 		Requester types.NodeID `json:"req"`
 		Type      string       `json:"type"`
 		Identity  []byte       `json:"id"`
@@ -105,6 +362,11 @@ func (req *PullRequest) UnmarshalJSON(data []byte) (err error) {
 	if err = json.Unmarshal(data, rawReq); err != nil {
 		return
 	}
+	if rawReq.Version > pullRequestVersion {
+		err = fmt.Errorf("unsupported pull request version: %d, expect %d",
+			rawReq.Version, pullRequestVersion)
+		return
+	}
 	var ID interface{}
 	switch rawReq.Type {
 	case "block":
@@ -139,14 +401,20 @@ type NetworkCensor interface {
 type censorClient struct {
 	TransportClient
 
-	censor NetworkCensor
-	lock   sync.RWMutex
+	censor      NetworkCensor
+	unreachable map[types.NodeID]struct{}
+	dropped     uint64
+	lock        sync.RWMutex
 }
 
 func (cc *censorClient) Send(ID types.NodeID, msg interface{}) error {
 	if func() bool {
 		cc.lock.RLock()
 		defer cc.lock.RUnlock()
+		if _, exists := cc.unreachable[ID]; exists {
+			atomic.AddUint64(&cc.dropped, 1)
+			return true
+		}
 		return cc.censor.Censor(msg)
 	}() {
 		return nil
@@ -159,6 +427,17 @@ func (cc *censorClient) Broadcast(
 	if func() bool {
 		cc.lock.RLock()
 		defer cc.lock.RUnlock()
+		if len(cc.unreachable) > 0 {
+			reachable := make(map[types.NodeID]struct{}, len(IDs))
+			for nID := range IDs {
+				if _, exists := cc.unreachable[nID]; exists {
+					atomic.AddUint64(&cc.dropped, 1)
+					continue
+				}
+				reachable[nID] = struct{}{}
+			}
+			IDs = reachable
+		}
 		return cc.censor.Censor(msg)
 	}() {
 		return nil
@@ -181,24 +460,55 @@ type Network struct {
 	fromTransport        <-chan *TransportEnvelope
 	toConsensus          chan types.Msg
 	toNode               chan interface{}
+	toApp                chan []byte
+	unhandledErrs        chan error
+	droppedUnhandled     uint64
+	droppedBusy          uint64
+	droppedAppMsgs       uint64
 	badPeerChan          chan interface{}
 	sentAgreementLock    sync.Mutex
-	sentAgreement        map[common.Hash]struct{}
+	sentAgreement        map[common.Hash]time.Time
+	evictedAgreement     map[common.Hash]struct{}
+	remarkedAgreements   uint64
+	agreementRates       map[types.Position]*agreementResultRate
+	droppedAgreements    uint64
+	implausibleResults   uint64
+	round                uint64
+	roundSet             uint32
 	blockCacheLock       sync.RWMutex
 	blockCache           map[common.Hash]*types.Block
+	blockCacheHooksLock  sync.RWMutex
+	onBlockEvicted       func(*types.Block)
+	onBlockMissed        func(common.Hash) *types.Block
 	voteCacheLock        sync.RWMutex
 	voteCache            map[types.Position]map[types.VoteHeader]*types.Vote
 	voteCacheSize        int
 	votePositions        []types.Position
 	stateModule          *State
+	peersLock            sync.RWMutex
 	peers                map[types.NodeID]struct{}
+	peersFixed           bool
 	unreceivedBlocksLock sync.RWMutex
 	unreceivedBlocks     map[common.Hash]chan<- common.Hash
 	cache                *utils.NodeSetCache
 	notarySetCachesLock  sync.Mutex
 	notarySetCaches      map[uint64]map[types.NodeID]struct{}
+	complementSetCaches  map[uint64]map[types.NodeID]struct{}
 	censor               NetworkCensor
 	censorLock           sync.RWMutex
+	pullLock             sync.Mutex
+	activePulls          int
+	queuedBlockPulls     map[common.Hash]struct{}
+	queuedVotePulls      map[types.Position]struct{}
+	blockChunksLock      sync.Mutex
+	blockChunks          map[common.Hash]*pendingBlockChunks
+	receiptsLock         sync.Mutex
+	receipts             map[common.Hash]chan<- *TransportEnvelope
+	pullRequestRatesLock sync.Mutex
+	pullRequestRates     map[types.NodeID]*pullRequestRate
+	throttledPulls       uint64
+	peerScoresLock       sync.Mutex
+	peerScores           map[types.NodeID]*peerScore
 }
 
 // NewNetwork setup network stuffs for nodes, which provides an
@@ -211,15 +521,60 @@ func NewNetwork(pubKey crypto.PublicKey, config NetworkConfig) (
 		config:           config,
 		toConsensus:      make(chan types.Msg, 1000),
 		toNode:           make(chan interface{}, 1000),
+		toApp:            make(chan []byte, 1000),
+		unhandledErrs:    make(chan error, 1000),
 		badPeerChan:      make(chan interface{}, 1000),
-		sentAgreement:    make(map[common.Hash]struct{}),
+		sentAgreement:    make(map[common.Hash]time.Time),
+		evictedAgreement: make(map[common.Hash]struct{}),
+		agreementRates:   make(map[types.Position]*agreementResultRate),
 		blockCache:       make(map[common.Hash]*types.Block, maxBlockCache),
 		unreceivedBlocks: make(map[common.Hash]chan<- common.Hash),
 		peers:            make(map[types.NodeID]struct{}),
 		notarySetCaches:  make(map[uint64]map[types.NodeID]struct{}),
+		complementSetCaches: make(
+			map[uint64]map[types.NodeID]struct{}),
 		voteCache: make(
 			map[types.Position]map[types.VoteHeader]*types.Vote),
-		censor: &dummyCensor{},
+		censor:           &dummyCensor{},
+		onBlockEvicted:   func(*types.Block) {},
+		onBlockMissed:    func(common.Hash) *types.Block { return nil },
+		queuedBlockPulls: make(map[common.Hash]struct{}),
+		queuedVotePulls:  make(map[types.Position]struct{}),
+		blockChunks:      make(map[common.Hash]*pendingBlockChunks),
+		receipts:         make(map[common.Hash]chan<- *TransportEnvelope),
+		peerScores:       make(map[types.NodeID]*peerScore),
+		pullRequestRates: make(map[types.NodeID]*pullRequestRate),
+	}
+	if n.config.MaxConcurrentPulls == 0 {
+		n.config.MaxConcurrentPulls = defaultMaxConcurrentPulls
+	}
+	if n.config.VotePositionWindow == 0 {
+		n.config.VotePositionWindow = defaultVotePositionWindow
+	}
+	if n.config.MaxBlocksPerPull == 0 {
+		n.config.MaxBlocksPerPull = defaultMaxBlocksPerPull
+	}
+	if n.config.MaxPullRequestsPerPeer == 0 {
+		n.config.MaxPullRequestsPerPeer = defaultMaxPullRequestsPerPeer
+	}
+	if n.config.PullRequestRateWindow == 0 {
+		n.config.PullRequestRateWindow = defaultPullRequestRateWindow
+	}
+	if n.config.DispatchWorkers == 0 {
+		n.config.DispatchWorkers = defaultDispatchWorkers
+	}
+	if n.config.UnhandledMsgPolicy == "" {
+		n.config.UnhandledMsgPolicy = UnhandledMsgForward
+	}
+	if n.config.MaxSendRetries == 0 {
+		n.config.MaxSendRetries = defaultMaxSendRetries
+	}
+	if n.config.SentMarkerTTL == 0 {
+		n.config.SentMarkerTTL = defaultSentMarkerTTL
+	}
+	if n.config.MaxAgreementResultsPerPosition == 0 {
+		n.config.MaxAgreementResultsPerPosition =
+			defaultMaxAgreementResultsPerPosition
 	}
 	n.ctx, n.ctxCancel = context.WithCancel(context.Background())
 	// Construct transport layer.
@@ -231,12 +586,14 @@ func NewNetwork(pubKey crypto.PublicKey, config NetworkConfig) (
 		trans = NewTCPTransportClient(pubKey, config.Marshaller, false)
 	case NetworkTypeFake:
 		trans = NewFakeTransportClient(pubKey)
+		trans.(*FakeTransport).SetMaxMessageAge(config.MaxMessageAge)
 	default:
 		panic(fmt.Errorf("unknown network type: %v", config.Type))
 	}
 	n.trans = &censorClient{
 		TransportClient: trans,
 		censor:          &dummyCensor{},
+		unreachable:     make(map[types.NodeID]struct{}),
 	}
 	return
 }
@@ -261,14 +618,96 @@ func (n *Network) SetCensor(censorIn, censorOut NetworkCensor) {
 	}()
 }
 
+// SetBlockCacheHooks sets callbacks of the block cache: onEvict is called
+// with each block evicted from the cache, and onMiss is consulted when a
+// pulled block is not cached, a nil return means that block is unknown.
+// Evicted blocks might still be shared with transport layer, onEvict should
+// not modify them. Passing nil for any of them resets it to a no-op.
+func (n *Network) SetBlockCacheHooks(
+	onEvict func(*types.Block), onMiss func(common.Hash) *types.Block) {
+	if onEvict == nil {
+		onEvict = func(*types.Block) {}
+	}
+	if onMiss == nil {
+		onMiss = func(common.Hash) *types.Block { return nil }
+	}
+	n.blockCacheHooksLock.Lock()
+	defer n.blockCacheHooksLock.Unlock()
+	n.onBlockEvicted = onEvict
+	n.onBlockMissed = onMiss
+}
+
+func (n *Network) blockCacheHooks() (
+	func(*types.Block), func(common.Hash) *types.Block) {
+	n.blockCacheHooksLock.RLock()
+	defer n.blockCacheHooksLock.RUnlock()
+	return n.onBlockEvicted, n.onBlockMissed
+}
+
 // PullBlocks implements core.Network interface.
 func (n *Network) PullBlocks(hashes common.Hashes) {
-	go n.pullBlocksAsync(hashes)
+	n.pullLock.Lock()
+	defer n.pullLock.Unlock()
+	if n.activePulls >= n.config.MaxConcurrentPulls {
+		for _, h := range hashes {
+			n.queuedBlockPulls[h] = struct{}{}
+		}
+		return
+	}
+	n.activePulls++
+	go n.pullRoutine(func() { n.pullBlocksAsync(hashes) })
 }
 
 // PullVotes implements core.Network interface.
 func (n *Network) PullVotes(pos types.Position) {
-	go n.pullVotesAsync(pos)
+	n.pullLock.Lock()
+	defer n.pullLock.Unlock()
+	if n.activePulls >= n.config.MaxConcurrentPulls {
+		n.queuedVotePulls[pos] = struct{}{}
+		return
+	}
+	n.activePulls++
+	go n.pullRoutine(func() { n.pullVotesAsync(pos) })
+}
+
+// Stats returns a snapshot of statistics of this network module.
+func (n *Network) Stats() (stats NetworkStats) {
+	func() {
+		n.pullLock.Lock()
+		defer n.pullLock.Unlock()
+		stats.ActivePulls = n.activePulls
+		stats.QueuedPulls = len(n.queuedBlockPulls) + len(n.queuedVotePulls)
+	}()
+	func() {
+		n.pullRequestRatesLock.Lock()
+		defer n.pullRequestRatesLock.Unlock()
+		stats.ThrottledPullRequests = n.throttledPulls
+	}()
+	func() {
+		n.sentAgreementLock.Lock()
+		defer n.sentAgreementLock.Unlock()
+		stats.DroppedAgreementResults = n.droppedAgreements
+		stats.RemarkedAgreementResults = n.remarkedAgreements
+	}()
+	func() {
+		n.peerScoresLock.Lock()
+		defer n.peerScoresLock.Unlock()
+		now := time.Now()
+		stats.PeerScores = make(map[types.NodeID]float64, len(n.peerScores))
+		for nID, s := range n.peerScores {
+			stats.PeerScores[nID] = s.decayed(now)
+		}
+	}()
+	stats.DroppedUnreachableMsgs = atomic.LoadUint64(&n.trans.dropped)
+	stats.DroppedUnhandledMsgs = atomic.LoadUint64(&n.droppedUnhandled)
+	stats.ImplausibleAgreementResults = atomic.LoadUint64(
+		&n.implausibleResults)
+	stats.DroppedBusyMsgs = atomic.LoadUint64(&n.droppedBusy)
+	stats.DroppedAppMsgs = atomic.LoadUint64(&n.droppedAppMsgs)
+	if fake, ok := n.trans.TransportClient.(*FakeTransport); ok {
+		stats.DroppedStaleMsgs = fake.DroppedStaleMsgs()
+	}
+	return
 }
 
 // BroadcastVote implements core.Network interface.
@@ -282,29 +721,106 @@ func (n *Network) BroadcastVote(vote *types.Vote) {
 
 // BroadcastBlock implements core.Network interface.
 func (n *Network) BroadcastBlock(block *types.Block) {
-	// Avoid data race in fake transport.
-	block = n.cloneForFake(block).(*types.Block)
+	// The copy is shared by transport layer and block cache.
+	block = block.Clone()
+	if err := n.broadcastBlock(block, n.splitBlock(block)); err != nil {
+		panic(err)
+	}
+}
+
+// broadcastBlock sends messages of a block, which are the block itself or its
+// chunks, to notary set and gossips them to others.
+func (n *Network) broadcastBlock(
+	block *types.Block, msgs []interface{}) error {
 	notarySet := n.getNotarySet(block.Position.Round)
-	if !block.IsFinalized() {
+	for _, msg := range msgs {
+		if !block.IsFinalized() {
+			if err := n.trans.Broadcast(
+				notarySet, n.config.DirectLatency, msg); err != nil {
+				return err
+			}
+		}
+		if n.config.DisableGossip {
+			continue
+		}
 		if err := n.trans.Broadcast(
-			notarySet, n.config.DirectLatency, block); err != nil {
-			panic(err)
+			n.getNotarySetComplement(block.Position.Round),
+			n.config.GossipLatency, msg); err != nil {
+			return err
 		}
 	}
-	if err := n.trans.Broadcast(getComplementSet(n.peers, notarySet),
-		n.config.GossipLatency, block); err != nil {
-		panic(err)
-	}
 	n.addBlockToCache(block)
-	if block.IsFinalized() {
-		n.addBlockRandomnessToCache(block.Hash, block.Randomness)
+	return nil
+}
+
+// BroadcastBlockWithConfirmation broadcasts a block like BroadcastBlock, and
+// waits until at least 'minConfirm' peers confirm receipt of it or timeout.
+// When the block is sent in chunks, a peer is confirmed after receiving all of
+// them. The count of confirmed peers is returned, ErrNotEnoughConfirmation is
+// returned when it's less than 'minConfirm'.
+func (n *Network) BroadcastBlockWithConfirmation(
+	block *types.Block, minConfirm int, timeout time.Duration) (int, error) {
+	// The copy is shared by transport layer and block cache.
+	block = block.Clone()
+	var (
+		msgs = n.splitBlock(block)
+		ch   = make(chan *TransportEnvelope, len(n.getPeers())*len(msgs))
+		reqs = make([]interface{}, 0, len(msgs))
+	)
+	func() {
+		n.receiptsLock.Lock()
+		defer n.receiptsLock.Unlock()
+		for _, msg := range msgs {
+			req := &receiptRequest{ID: common.NewRandomHash(), Msg: msg}
+			n.receipts[req.ID] = ch
+			reqs = append(reqs, req)
+		}
+	}()
+	defer func() {
+		n.receiptsLock.Lock()
+		defer n.receiptsLock.Unlock()
+		for _, req := range reqs {
+			delete(n.receipts, req.(*receiptRequest).ID)
+		}
+	}()
+	if err := n.broadcastBlock(block, reqs); err != nil {
+		return 0, err
 	}
+	var (
+		// Receipts are deduplicated by request ID, a peer replying the
+		// receipt of one chunk many times is not confirmed.
+		receipts  = make(map[types.NodeID]map[common.Hash]struct{})
+		confirmed = make(map[types.NodeID]struct{})
+	)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+Loop:
+	for len(confirmed) < minConfirm {
+		select {
+		case e := <-ch:
+			if _, exists := receipts[e.From]; !exists {
+				receipts[e.From] = make(map[common.Hash]struct{})
+			}
+			receipts[e.From][e.Msg.(*receipt).ID] = struct{}{}
+			if len(receipts[e.From]) == len(reqs) {
+				confirmed[e.From] = struct{}{}
+			}
+		case <-timer.C:
+			break Loop
+		case <-n.ctx.Done():
+			break Loop
+		}
+	}
+	if len(confirmed) < minConfirm {
+		return len(confirmed), ErrNotEnoughConfirmation
+	}
+	return len(confirmed), nil
 }
 
 // BroadcastAgreementResult implements core.Network interface.
 func (n *Network) BroadcastAgreementResult(
 	result *types.AgreementResult) {
-	if !n.markAgreementResultAsSent(result.BlockHash) {
+	if !n.markAgreementResultAsSent(result.BlockHash, result.Position) {
 		return
 	}
 	n.addBlockRandomnessToCache(result.BlockHash, result.Randomness)
@@ -318,8 +834,12 @@ func (n *Network) BroadcastAgreementResult(
 			panic(err)
 		}
 	}
+	if n.config.DisableGossip {
+		return
+	}
 	// Gossip to other nodes.
-	if err := n.trans.Broadcast(getComplementSet(n.peers, notarySet),
+	if err := n.trans.Broadcast(
+		n.getNotarySetComplement(result.Position.Round),
 		n.config.GossipLatency, result); err != nil {
 		panic(err)
 	}
@@ -328,7 +848,63 @@ func (n *Network) BroadcastAgreementResult(
 // SendDKGPrivateShare implements core.Network interface.
 func (n *Network) SendDKGPrivateShare(
 	recv crypto.PublicKey, prvShare *typesDKG.PrivateShare) {
-	n.send(types.NewNodeID(recv), prvShare)
+	if !n.config.ReliablePrivateShare {
+		n.send(types.NewNodeID(recv), prvShare)
+		return
+	}
+	go func() {
+		// The receiver would complain about the missing private share when
+		// it's not confirmed, nothing more to do here.
+		n.SendReliable(types.NewNodeID(recv), prvShare,
+			reliablePrivateShareTimeout) // #nosec G104
+	}()
+}
+
+// SendReliable sends a message to a peer and waits for its receipt, the
+// message would be resent when the receipt is not confirmed within timeout,
+// up to MaxSendRetries times. The receiver may receive duplicated messages.
+func (n *Network) SendReliable(
+	endpoint types.NodeID, msg interface{}, timeout time.Duration) error {
+	req := &receiptRequest{ID: common.NewRandomHash(), Msg: msg}
+	ch := make(chan *TransportEnvelope, 1)
+	func() {
+		n.receiptsLock.Lock()
+		defer n.receiptsLock.Unlock()
+		n.receipts[req.ID] = ch
+	}()
+	defer func() {
+		n.receiptsLock.Lock()
+		defer n.receiptsLock.Unlock()
+		delete(n.receipts, req.ID)
+	}()
+	for i := 0; i <= n.config.MaxSendRetries; i++ {
+		time.Sleep(n.config.DirectLatency.Delay())
+		if err := n.trans.Send(endpoint, req); err != nil {
+			return err
+		}
+		if func() bool {
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			for {
+				select {
+				case e := <-ch:
+					if e.From == endpoint {
+						return true
+					}
+				case <-timer.C:
+					return false
+				case <-n.ctx.Done():
+					return false
+				}
+			}
+		}() {
+			return nil
+		}
+		if err := n.ctx.Err(); err != nil {
+			return err
+		}
+	}
+	return ErrSendNotConfirmed
 }
 
 // BroadcastDKGPrivateShare implements core.Network interface.
@@ -359,24 +935,86 @@ func (n *Network) Setup(serverEndpoint interface{}) (err error) {
 	// Join the p2p network.
 	switch n.config.Type {
 	case NetworkTypeTCP, NetworkTypeTCPLocal:
-		addr := net.JoinHostPort(
+		serverEndpoint = net.JoinHostPort(
 			n.config.PeerServer, strconv.Itoa(n.config.PeerPort))
-		n.fromTransport, err = n.trans.Join(addr)
 	case NetworkTypeFake:
-		n.fromTransport, err = n.trans.Join(serverEndpoint)
 	default:
 		err = fmt.Errorf("unknown network type: %v", n.config.Type)
+		return
 	}
-	if err != nil {
+	if n.fromTransport, err = n.join(serverEndpoint); err != nil {
 		return
 	}
-	peerKeys := n.trans.Peers()
-	for _, k := range peerKeys {
-		n.peers[types.NewNodeID(k)] = struct{}{}
+	if n.peersFixed {
+		return
 	}
+	peerKeys := n.trans.Peers()
+	func() {
+		n.peersLock.Lock()
+		defer n.peersLock.Unlock()
+		// Peers are replaced instead of modified, see getPeers.
+		peers := make(map[types.NodeID]struct{}, len(n.peers)+len(peerKeys))
+		for nID := range n.peers {
+			peers[nID] = struct{}{}
+		}
+		for _, k := range peerKeys {
+			peers[types.NewNodeID(k)] = struct{}{}
+		}
+		n.peers = peers
+	}()
+	n.purgeComplementSetCaches()
 	return
 }
 
+func (n *Network) join(serverEndpoint interface{}) (
+	<-chan *TransportEnvelope, error) {
+	if n.config.JoinTimeout == 0 {
+		return n.trans.Join(context.Background(), serverEndpoint)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(),
+		n.config.JoinTimeout)
+	defer cancel()
+	ch, err := n.trans.Join(ctx, serverEndpoint)
+	if err == nil {
+		return ch, nil
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		return nil, err
+	}
+	// Join has returned, it's safe to release the transport now.
+	// #nosec G104
+	n.trans.Close()
+	return nil, fmt.Errorf("%s: unable to join %v in %v: %v",
+		ErrJoinTimeout, serverEndpoint, n.config.JoinTimeout, err)
+}
+
+// SetPeers sets peers of this network module directly instead of learning
+// them from the transport layer in Setup. Setup is still required to join the
+// transport layer for sending messages, and it won't overwrite peers set here.
+// It should be called before Setup.
+func (n *Network) SetPeers(keys []crypto.PublicKey) {
+	peers := make(map[types.NodeID]struct{}, len(keys))
+	for _, k := range keys {
+		peers[types.NewNodeID(k)] = struct{}{}
+	}
+	func() {
+		n.peersLock.Lock()
+		defer n.peersLock.Unlock()
+		n.peers = peers
+	}()
+	n.peersFixed = true
+	n.purgeComplementSetCaches()
+}
+
+// getPeers returns peers of this network module. Peers are replaced instead of
+// modified, thus the returned map could be read without lock but should not be
+// modified.
+func (n *Network) getPeers() map[types.NodeID]struct{} {
+	n.peersLock.RLock()
+	defer n.peersLock.RUnlock()
+	return n.peers
+}
+
 func (n *Network) dispatchMsg(e *TransportEnvelope) {
 	if func() bool {
 		n.censorLock.RLock()
@@ -386,9 +1024,19 @@ func (n *Network) dispatchMsg(e *TransportEnvelope) {
 		return
 	}
 	msg := n.cloneForFake(e.Msg)
+	if req, ok := msg.(*receiptRequest); ok {
+		n.send(e.From, &receipt{ID: req.ID})
+		msg = n.cloneForFake(req.Msg)
+	}
+	if chunk, ok := msg.(*blockChunk); ok {
+		if msg = n.assembleBlockChunk(chunk); msg == nil {
+			return
+		}
+	}
 	switch v := msg.(type) {
 	case *types.Block:
-		n.addBlockToCache(v)
+		// The block is also passed to consensus core, which might modify it.
+		n.addBlockToCache(v.Clone())
 		// Notify pulling routine about the newly arrived block.
 		func() {
 			n.unreceivedBlocksLock.Lock()
@@ -409,48 +1057,140 @@ func (n *Network) dispatchMsg(e *TransportEnvelope) {
 			PeerID:  e.From,
 			Payload: v,
 		}
-	case *types.AgreementResult,
-		*typesDKG.PrivateShare, *typesDKG.PartialSignature:
+	case *types.AgreementResult:
+		if !n.isPlausibleRound(v.Position.Round) {
+			atomic.AddUint64(&n.implausibleResults, 1)
+			return
+		}
+		n.toConsensus <- types.Msg{
+			PeerID:  e.From,
+			Payload: v,
+		}
+	case *typesDKG.PrivateShare, *typesDKG.PartialSignature:
 		n.toConsensus <- types.Msg{
 			PeerID:  e.From,
 			Payload: v,
 		}
 	case packedStateChanges:
 		if n.stateModule == nil {
-			panic(errors.New(
-				"receive packed state change request without state attached"))
+			n.handleUnhandledMsg(e.From, v)
+			return
 		}
 		if err := n.stateModule.AddRequestsFromOthers([]byte(v)); err != nil {
 			panic(err)
 		}
 	case *PullRequest:
-		go n.handlePullRequest(v)
+		// Serving pull requests might be slow, don't occupy dispatching
+		// routines. They are bounded by per-peer rate limiting.
+		go n.handlePullRequest(e.From, v)
+	case *appMessage:
+		// Most users never drain app messages, don't let them block others.
+		select {
+		case n.toApp <- common.CopyBytes(v.Payload):
+		default:
+			atomic.AddUint64(&n.droppedAppMsgs, 1)
+		}
+	case *receipt:
+		func() {
+			n.receiptsLock.Lock()
+			defer n.receiptsLock.Unlock()
+			if ch, exists := n.receipts[v.ID]; exists {
+				select {
+				case ch <- e:
+				default:
+				}
+			}
+		}()
 	default:
-		n.toNode <- v
+		n.handleUnhandledMsg(e.From, v)
 	}
 }
 
-func (n *Network) handlePullRequest(req *PullRequest) {
+func (n *Network) handleUnhandledMsg(from types.NodeID, msg interface{}) {
+	switch n.config.UnhandledMsgPolicy {
+	case UnhandledMsgForward:
+		n.toNode <- msg
+	case UnhandledMsgDrop:
+		atomic.AddUint64(&n.droppedUnhandled, 1)
+	case UnhandledMsgReport:
+		atomic.AddUint64(&n.droppedUnhandled, 1)
+		select {
+		case n.unhandledErrs <- fmt.Errorf(
+			"%s: %T from %s", ErrUnhandledMessage, msg, from):
+		default:
+		}
+	default:
+		panic(fmt.Errorf(
+			"unknown unhandled message policy: %v", n.config.UnhandledMsgPolicy))
+	}
+}
+
+// allowPullRequest checks if a pull request from that peer should be served
+// under per-peer rate limiting.
+func (n *Network) allowPullRequest(from types.NodeID) bool {
+	n.pullRequestRatesLock.Lock()
+	defer n.pullRequestRatesLock.Unlock()
+	now := time.Now()
+	rate, exist := n.pullRequestRates[from]
+	if !exist || now.Sub(rate.since) > n.config.PullRequestRateWindow {
+		// Prune expired records when starting a new window.
+		for nID, r := range n.pullRequestRates {
+			if now.Sub(r.since) > n.config.PullRequestRateWindow {
+				delete(n.pullRequestRates, nID)
+			}
+		}
+		rate = &pullRequestRate{since: now}
+		n.pullRequestRates[from] = rate
+	}
+	if rate.count >= n.config.MaxPullRequestsPerPeer {
+		n.throttledPulls++
+		return false
+	}
+	rate.count++
+	return true
+}
+
+// handlePullRequest serves a pull request received from that peer. Requester
+// in the request is not trusted, responses are always sent to the sender.
+func (n *Network) handlePullRequest(from types.NodeID, req *PullRequest) {
+	if !n.allowPullRequest(from) {
+		return
+	}
 	switch req.Type {
 	case "block":
 		hashes := req.Identity.(common.Hashes)
+		if len(hashes) > n.config.MaxBlocksPerPull {
+			hashes = hashes[:n.config.MaxBlocksPerPull]
+		}
+		// Blocks are collected under lock, the hook and sending are done
+		// after unlocking, thus they could call back into this module.
+		blocks := make([]*types.Block, len(hashes))
 		func() {
-			n.blockCacheLock.Lock()
-			defer n.blockCacheLock.Unlock()
-		All:
-			for _, h := range hashes {
-				b, exists := n.blockCache[h]
-				if !exists {
-					continue
+			n.blockCacheLock.RLock()
+			defer n.blockCacheLock.RUnlock()
+			for idx, h := range hashes {
+				if b, exists := n.blockCache[h]; exists {
+					blocks[idx] = b
 				}
-				select {
-				case <-n.ctx.Done():
-					break All
-				default:
-				}
-				n.send(req.Requester, b)
 			}
 		}()
+		_, onMiss := n.blockCacheHooks()
+	All:
+		for idx, b := range blocks {
+			if b == nil {
+				if b = onMiss(hashes[idx]); b == nil {
+					continue
+				}
+			}
+			select {
+			case <-n.ctx.Done():
+				break All
+			default:
+			}
+			for _, msg := range n.splitBlock(b) {
+				n.send(from, msg)
+			}
+		}
 	case "vote":
 		pos := req.Identity.(types.Position)
 		func() {
@@ -458,7 +1198,7 @@ func (n *Network) handlePullRequest(req *PullRequest) {
 			defer n.voteCacheLock.Unlock()
 			if votes, exists := n.voteCache[pos]; exists {
 				for _, v := range votes {
-					n.send(req.Requester, v)
+					n.send(from, v)
 				}
 			}
 		}()
@@ -469,6 +1209,16 @@ func (n *Network) handlePullRequest(req *PullRequest) {
 
 // Run the main loop.
 func (n *Network) Run() {
+	go n.sweepSentMarkers()
+	dispatchQueue := make(chan *TransportEnvelope, dispatchQueueSize)
+	defer close(dispatchQueue)
+	for i := 0; i < n.config.DispatchWorkers; i++ {
+		go func() {
+			for e := range dispatchQueue {
+				n.dispatchMsg(e)
+			}
+		}()
+	}
 Loop:
 	for {
 		select {
@@ -488,7 +1238,13 @@ Loop:
 			if !ok {
 				break Loop
 			}
-			go n.dispatchMsg(e)
+			select {
+			case dispatchQueue <- e:
+			default:
+				// Don't block reading from transport layer when all workers
+				// are busy, or one slow handler would stall all traffic.
+				atomic.AddUint64(&n.droppedBusy, 1)
+			}
 		}
 	}
 }
@@ -500,6 +1256,8 @@ func (n *Network) Close() (err error) {
 	n.toConsensus = nil
 	close(n.toNode)
 	n.toNode = nil
+	close(n.toApp)
+	n.toApp = nil
 	if err = n.trans.Close(); err != nil {
 		return
 	}
@@ -513,7 +1271,14 @@ func (n *Network) Report(msg interface{}) error {
 
 // Broadcast a message to all peers.
 func (n *Network) Broadcast(msg interface{}) error {
-	return n.trans.Broadcast(n.peers, &FixedLatencyModel{}, msg)
+	return n.trans.Broadcast(n.getPeers(), &FixedLatencyModel{}, msg)
+}
+
+// BroadcastAppMessage broadcasts a message between applications to all peers,
+// it's received from the channel returned by AppMessageChan of them.
+func (n *Network) BroadcastAppMessage(payload []byte) error {
+	return n.trans.Broadcast(n.getPeers(), &FixedLatencyModel{},
+		&appMessage{Payload: common.CopyBytes(payload)})
 }
 
 // Peers exports 'Peers' method of Transport.
@@ -532,6 +1297,19 @@ func (n *Network) ReceiveChanForNode() <-chan interface{} {
 	return n.toNode
 }
 
+// AppMessageChan returns a channel for messages broadcasted by
+// BroadcastAppMessage of peers. Messages received when the channel is full
+// are dropped, and counted in NetworkStats.DroppedAppMsgs.
+func (n *Network) AppMessageChan() <-chan []byte {
+	return n.toApp
+}
+
+// UnhandledMsgErrChan returns a channel for errors of messages not handled by
+// network module, it's only used with UnhandledMsgReport policy.
+func (n *Network) UnhandledMsgErrChan() <-chan error {
+	return n.unhandledErrs
+}
+
 // addStateModule attaches a State instance to this network.
 func (n *Network) addStateModule(s *State) {
 	// This variable should be attached before run, no lock to protect it.
@@ -546,9 +1324,47 @@ func (n *Network) AttachNodeSetCache(cache *utils.NodeSetCache) {
 	n.cache = cache
 }
 
+// SetCurrentRound updates the current round of the attached consensus core,
+// which is used to check rounds of received agreement results. See
+// NetworkConfig.AgreementResultRoundWindow. It should be called whenever the
+// consensus core enters a new round, e.g. in a handler registered to
+// utils.RoundEvent.
+func (n *Network) SetCurrentRound(round uint64) {
+	atomic.StoreUint64(&n.round, round)
+	atomic.StoreUint32(&n.roundSet, 1)
+}
+
+// isPlausibleRound checks if a round is within
+// NetworkConfig.AgreementResultRoundWindow of the current round.
+func (n *Network) isPlausibleRound(round uint64) bool {
+	window := n.config.AgreementResultRoundWindow
+	if window == 0 || atomic.LoadUint32(&n.roundSet) == 0 {
+		return true
+	}
+	cur := atomic.LoadUint64(&n.round)
+	return round+window >= cur && round <= cur+window
+}
+
 // PurgeNodeSetCache purges cache of some round in attached utils.NodeSetCache.
 func (n *Network) PurgeNodeSetCache(round uint64) {
 	n.cache.Purge(round)
+	n.notarySetCachesLock.Lock()
+	defer n.notarySetCachesLock.Unlock()
+	delete(n.notarySetCaches, round)
+	delete(n.complementSetCaches, round)
+}
+
+// SetPeerReachable marks a peer as reachable or not, messages sent to an
+// unreachable peer would be dropped silently, which is useful to simulate
+// disconnections.
+func (n *Network) SetPeerReachable(nID types.NodeID, reachable bool) {
+	n.trans.lock.Lock()
+	defer n.trans.lock.Unlock()
+	if reachable {
+		delete(n.trans.unreachable, nID)
+	} else {
+		n.trans.unreachable[nID] = struct{}{}
+	}
 }
 
 // ReportBadPeerChan reports that a peer is sending bad message.
@@ -556,7 +1372,52 @@ func (n *Network) ReportBadPeerChan() chan<- interface{} {
 	return n.badPeerChan
 }
 
+// pullRoutine runs the pulling job, and keeps serving queued pull requests
+// until the queue is empty.
+func (n *Network) pullRoutine(job func()) {
+	for job != nil {
+		job()
+		job = func() func() {
+			n.pullLock.Lock()
+			defer n.pullLock.Unlock()
+			select {
+			case <-n.ctx.Done():
+				n.activePulls--
+				return nil
+			default:
+			}
+			// Queued block hashes are coalesced into one pull request.
+			if len(n.queuedBlockPulls) > 0 {
+				hashes := make(common.Hashes, 0, len(n.queuedBlockPulls))
+				for h := range n.queuedBlockPulls {
+					hashes = append(hashes, h)
+				}
+				n.queuedBlockPulls = make(map[common.Hash]struct{})
+				return func() { n.pullBlocksAsync(hashes) }
+			}
+			for pos := range n.queuedVotePulls {
+				delete(n.queuedVotePulls, pos)
+				return func() { n.pullVotesAsync(pos) }
+			}
+			n.activePulls--
+			return nil
+		}()
+	}
+}
+
 func (n *Network) pullBlocksAsync(hashes common.Hashes) {
+	// Peers won't serve more than MaxBlocksPerPull blocks in one request,
+	// queue the rest to be pulled by pullRoutine later.
+	if len(hashes) > n.config.MaxBlocksPerPull {
+		func() {
+			n.pullLock.Lock()
+			defer n.pullLock.Unlock()
+			for _, h := range hashes[n.config.MaxBlocksPerPull:] {
+				n.queuedBlockPulls[h] = struct{}{}
+			}
+		}()
+		hashes = hashes[:n.config.MaxBlocksPerPull]
+	}
 	// Setup notification channels for each block hash.
 	notYetReceived := make(map[common.Hash]struct{})
 	ch := make(chan common.Hash, len(hashes))
@@ -576,18 +1437,17 @@ func (n *Network) pullBlocksAsync(hashes common.Hashes) {
 		Type:      "block",
 		Identity:  hashes,
 	}
-	// Randomly pick peers to send pull requests.
+	// Send pull requests to responsive peers first.
 Loop:
-	for nID := range n.peers {
-		if nID == n.ID {
-			continue
-		}
+	for _, nID := range n.rankPeers(n.getPeers()) {
 		n.send(nID, req)
+		received := 0
 		select {
 		case <-n.ctx.Done():
 			break Loop
 		case <-time.After(2 * n.config.DirectLatency.Delay()):
 			// Consume everything in the notification channel.
+		Drain:
 			for {
 				select {
 				case h, ok := <-ch:
@@ -596,14 +1456,20 @@ Loop:
 						break Loop
 					}
 					delete(notYetReceived, h)
-					if len(notYetReceived) == 0 {
-						break Loop
-					}
+					received++
 				default:
-					continue Loop
+					break Drain
 				}
 			}
 		}
+		if received > 0 {
+			n.updatePeerScore(nID, peerScoreReward)
+		} else {
+			n.updatePeerScore(nID, -peerScorePenalty)
+		}
+		if len(notYetReceived) == 0 {
+			break
+		}
 	}
 }
 
@@ -616,9 +1482,9 @@ func (n *Network) pullVotesAsync(pos types.Position) {
 	}
 	// Get corresponding notary set.
 	notarySet := n.getNotarySet(pos.Round)
-	// Randomly select one peer from notary set and send a pull request.
+	// Select responsive peers from notary set and send pull requests.
 	sentCount := 0
-	for nID := range notarySet {
+	for _, nID := range n.rankPeers(notarySet) {
 		n.send(nID, req)
 		sentCount++
 		if sentCount >= maxPullingPeerCount {
@@ -627,17 +1493,153 @@ func (n *Network) pullVotesAsync(pos types.Position) {
 	}
 }
 
+// updatePeerScore adds delta to the decayed score of a peer.
+func (n *Network) updatePeerScore(nID types.NodeID, delta float64) {
+	n.peerScoresLock.Lock()
+	defer n.peerScoresLock.Unlock()
+	now := time.Now()
+	s, exists := n.peerScores[nID]
+	if !exists {
+		s = &peerScore{}
+		n.peerScores[nID] = s
+	}
+	s.value = s.decayed(now) + delta
+	s.updated = now
+}
+
+// rankPeers orders peers by their scores, the highest first. Peers with equal
+// scores are shuffled, and occasionally a random peer is moved to the front
+// to give low-score peers a chance to recover.
+func (n *Network) rankPeers(peers map[types.NodeID]struct{}) types.NodeIDs {
+	ranked := make(types.NodeIDs, 0, len(peers))
+	for nID := range peers {
+		if nID == n.ID {
+			continue
+		}
+		ranked = append(ranked, nID)
+	}
+	scores := make(map[types.NodeID]float64, len(ranked))
+	func() {
+		n.peerScoresLock.Lock()
+		defer n.peerScoresLock.Unlock()
+		now := time.Now()
+		for _, nID := range ranked {
+			if s, exists := n.peerScores[nID]; exists {
+				scores[nID] = s.decayed(now)
+			}
+		}
+	}()
+	rand.Shuffle(len(ranked), func(i, j int) {
+		ranked[i], ranked[j] = ranked[j], ranked[i]
+	})
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+	if len(ranked) > 1 && rand.Intn(100) < peerScoreExplorePercent {
+		idx := 1 + rand.Intn(len(ranked)-1)
+		ranked[0], ranked[idx] = ranked[idx], ranked[0]
+	}
+	return ranked
+}
+
+// splitBlock splits a block into chunks when its payload is larger than
+// BlockChunkSize, or returns the block itself.
+func (n *Network) splitBlock(b *types.Block) []interface{} {
+	size := n.config.BlockChunkSize
+	if n.config.Type == NetworkTypeFake || size <= 0 || len(b.Payload) <= size {
+		return []interface{}{b}
+	}
+	total := (len(b.Payload) + size - 1) / size
+	header := b.Clone()
+	header.Payload = nil
+	chunks := make([]interface{}, 0, total)
+	for idx := 0; idx < total; idx++ {
+		end := (idx + 1) * size
+		if end > len(b.Payload) {
+			end = len(b.Payload)
+		}
+		chunk := &blockChunk{
+			BlockHash: b.Hash,
+			Index:     idx,
+			Total:     total,
+			Data:      b.Payload[idx*size : end],
+		}
+		if idx == 0 {
+			chunk.Block = header
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// assembleBlockChunk collects a chunk and returns the block once all chunks of
+// it are received. Incomplete blocks would be dropped after
+// blockChunkTimeout.
+func (n *Network) assembleBlockChunk(chunk *blockChunk) *types.Block {
+	if chunk.Total <= 0 || chunk.Total > maxBlockChunkCount ||
+		chunk.Index < 0 || chunk.Index >= chunk.Total {
+		return nil
+	}
+	n.blockChunksLock.Lock()
+	defer n.blockChunksLock.Unlock()
+	now := time.Now()
+	for h, pending := range n.blockChunks {
+		if now.Sub(pending.since) > blockChunkTimeout {
+			delete(n.blockChunks, h)
+		}
+	}
+	pending, exists := n.blockChunks[chunk.BlockHash]
+	if !exists {
+		pending = &pendingBlockChunks{
+			data:  make([][]byte, chunk.Total),
+			since: now,
+		}
+		n.blockChunks[chunk.BlockHash] = pending
+	}
+	if len(pending.data) != chunk.Total || pending.data[chunk.Index] != nil {
+		return nil
+	}
+	if chunk.Block != nil {
+		if chunk.Block.Hash != chunk.BlockHash {
+			return nil
+		}
+		pending.block = chunk.Block
+	}
+	pending.data[chunk.Index] = common.CopyBytes(chunk.Data)
+	if pending.received++; pending.received < chunk.Total ||
+		pending.block == nil {
+		return nil
+	}
+	delete(n.blockChunks, chunk.BlockHash)
+	b := pending.block
+	for _, data := range pending.data {
+		b.Payload = append(b.Payload, data...)
+	}
+	return b
+}
+
+// addBlockToCache caches the block without copying it, callers should not
+// modify it afterwards. Cached blocks are never modified, thus they could be
+// shared with transport layer.
 func (n *Network) addBlockToCache(b *types.Block) {
-	n.blockCacheLock.Lock()
-	defer n.blockCacheLock.Unlock()
-	if len(n.blockCache) > maxBlockCache {
-		// Randomly purge one block from cache.
-		for k := range n.blockCache {
-			delete(n.blockCache, k)
-			break
+	var evicted *types.Block
+	func() {
+		n.blockCacheLock.Lock()
+		defer n.blockCacheLock.Unlock()
+		if len(n.blockCache) > maxBlockCache {
+			// Randomly purge one block from cache.
+			for k, v := range n.blockCache {
+				delete(n.blockCache, k)
+				evicted = v
+				break
+			}
 		}
+		n.blockCache[b.Hash] = b
+	}()
+	if evicted != nil {
+		onEvict, _ := n.blockCacheHooks()
+		onEvict(evicted)
 	}
-	n.blockCache[b.Hash] = b.Clone()
 }
 
 func (n *Network) addBlockRandomnessToCache(hash common.Hash, rand []byte) {
@@ -647,19 +1649,31 @@ func (n *Network) addBlockRandomnessToCache(hash common.Hash, rand []byte) {
 	if !exist {
 		return
 	}
-	block.Randomness = rand
+	// Replace the cached one with a copy to keep cached blocks unmodified.
+	updated := *block
+	updated.Randomness = rand
+	n.blockCache[hash] = &updated
 }
 
 func (n *Network) addVoteToCache(v *types.Vote) {
 	n.voteCacheLock.Lock()
 	defer n.voteCacheLock.Unlock()
-	if n.voteCacheSize >= maxVoteCache {
+	evictOldest := func() {
 		pos := n.votePositions[0]
 		n.voteCacheSize -= len(n.voteCache[pos])
 		delete(n.voteCache, pos)
-		n.votePositions = n.votePositions[1:]
+		// Shift instead of reslicing, or the backing array would keep
+		// growing.
+		copy(n.votePositions, n.votePositions[1:])
+		n.votePositions = n.votePositions[:len(n.votePositions)-1]
+	}
+	if n.voteCacheSize >= maxVoteCache {
+		evictOldest()
 	}
 	if _, exists := n.voteCache[v.Position]; !exists {
+		if len(n.votePositions) >= n.config.VotePositionWindow {
+			evictOldest()
+		}
 		n.votePositions = append(n.votePositions, v.Position)
 		n.voteCache[v.Position] =
 			make(map[types.VoteHeader]*types.Vote)
@@ -671,23 +1685,142 @@ func (n *Network) addVoteToCache(v *types.Vote) {
 	n.voteCacheSize++
 }
 
-func (n *Network) markAgreementResultAsSent(blockHash common.Hash) bool {
+// ExportVoteCache serializes votes cached for serving pull requests, thus they
+// could be imported by ImportVoteCache after restarting. Votes of the latest
+// positions are exported, at most maxVoteCache votes.
+func (n *Network) ExportVoteCache() ([]byte, error) {
+	snapshot := voteCacheSnapshot{Version: voteCacheSnapshotVersion}
+	func() {
+		n.voteCacheLock.RLock()
+		defer n.voteCacheLock.RUnlock()
+		begin := len(n.votePositions)
+		for count := 0; begin > 0; begin-- {
+			count += len(n.voteCache[n.votePositions[begin-1]])
+			if count > maxVoteCache {
+				break
+			}
+		}
+		for _, pos := range n.votePositions[begin:] {
+			for _, v := range n.voteCache[pos] {
+				snapshot.Votes = append(snapshot.Votes, v)
+			}
+		}
+	}()
+	return json.Marshal(&snapshot)
+}
+
+// ImportVoteCache loads votes exported by ExportVoteCache into the vote cache,
+// limits of the vote cache are still applied.
+func (n *Network) ImportVoteCache(data []byte) error {
+	snapshot := voteCacheSnapshot{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	if snapshot.Version != voteCacheSnapshotVersion {
+		return ErrVoteCacheVersionMismatch
+	}
+	for _, v := range snapshot.Votes {
+		if v == nil {
+			continue
+		}
+		n.addVoteToCache(v)
+	}
+	return nil
+}
+
+func (n *Network) markAgreementResultAsSent(
+	blockHash common.Hash, pos types.Position) bool {
 	n.sentAgreementLock.Lock()
 	defer n.sentAgreementLock.Unlock()
 	if _, exist := n.sentAgreement[blockHash]; exist {
 		return false
 	}
-	if len(n.sentAgreement) > 1000 {
-		// Randomly drop one entry.
-		for k := range n.sentAgreement {
-			delete(n.sentAgreement, k)
-			break
+	// Limit distinct agreement results gossiped for one position.
+	now := time.Now()
+	rate, exist := n.agreementRates[pos]
+	if !exist || now.Sub(rate.since) > agreementResultRateWindow {
+		if len(n.agreementRates) > 1000 {
+			for p, r := range n.agreementRates {
+				if now.Sub(r.since) > agreementResultRateWindow {
+					delete(n.agreementRates, p)
+				}
+			}
 		}
+		rate = &agreementResultRate{since: now}
+		n.agreementRates[pos] = rate
+	}
+	if rate.count >= n.config.MaxAgreementResultsPerPosition {
+		n.droppedAgreements++
+		return false
+	}
+	rate.count++
+	if _, evicted := n.evictedAgreement[blockHash]; evicted {
+		n.remarkedAgreements++
+		delete(n.evictedAgreement, blockHash)
 	}
-	n.sentAgreement[blockHash] = struct{}{}
+	if len(n.sentAgreement) >= maxSentAgreementCache {
+		// Drop the oldest entry.
+		var (
+			oldestHash common.Hash
+			oldestTime time.Time
+		)
+		for h, t := range n.sentAgreement {
+			if oldestTime.IsZero() || t.Before(oldestTime) {
+				oldestHash, oldestTime = h, t
+			}
+		}
+		n.evictSentMarkerWithoutLock(oldestHash)
+	}
+	n.sentAgreement[blockHash] = now
 	return true
 }
 
+// evictSentMarkerWithoutLock removes a marker of sent agreement result, and
+// remembers it to detect if that result is gossiped again.
+func (n *Network) evictSentMarkerWithoutLock(blockHash common.Hash) {
+	delete(n.sentAgreement, blockHash)
+	if len(n.evictedAgreement) >= maxSentAgreementCache {
+		n.evictedAgreement = make(map[common.Hash]struct{})
+	}
+	n.evictedAgreement[blockHash] = struct{}{}
+}
+
+// SentMarkersSnapshot returns hashes of agreement results marked as sent.
+func (n *Network) SentMarkersSnapshot() common.Hashes {
+	n.sentAgreementLock.Lock()
+	defer n.sentAgreementLock.Unlock()
+	hashes := make(common.Hashes, 0, len(n.sentAgreement))
+	for h := range n.sentAgreement {
+		hashes = append(hashes, h)
+	}
+	return hashes
+}
+
+// purgeExpiredSentMarkers removes markers of sent agreement results older than
+// SentMarkerTTL.
+func (n *Network) purgeExpiredSentMarkers(now time.Time) {
+	n.sentAgreementLock.Lock()
+	defer n.sentAgreementLock.Unlock()
+	for h, t := range n.sentAgreement {
+		if now.Sub(t) > n.config.SentMarkerTTL {
+			n.evictSentMarkerWithoutLock(h)
+		}
+	}
+}
+
+func (n *Network) sweepSentMarkers() {
+	ticker := time.NewTicker(sentMarkerSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case now := <-ticker.C:
+			n.purgeExpiredSentMarkers(now)
+		}
+	}
+}
+
 func (n *Network) cloneForFake(v interface{}) interface{} {
 	if n.config.Type != NetworkTypeFake {
 		return v
@@ -707,7 +1840,7 @@ func (n *Network) getNotarySet(round uint64) map[types.NodeID]struct{} {
 	if n.cache == nil {
 		// Default behavior is to broadcast to all peers, which makes it easier
 		// to be used in simple test cases.
-		return n.peers
+		return n.getPeers()
 	}
 	n.notarySetCachesLock.Lock()
 	defer n.notarySetCachesLock.Unlock()
@@ -723,9 +1856,36 @@ func (n *Network) getNotarySet(round uint64) map[types.NodeID]struct{} {
 	return set
 }
 
+// getNotarySetComplement returns peers not in the notary set of that round. The
+// result is cached and shared, callers should not modify it.
+func (n *Network) getNotarySetComplement(
+	round uint64) map[types.NodeID]struct{} {
+	notarySet := n.getNotarySet(round)
+	n.notarySetCachesLock.Lock()
+	defer n.notarySetCachesLock.Unlock()
+	set, exists := n.complementSetCaches[round]
+	if !exists {
+		set = getComplementSet(n.getPeers(), notarySet)
+		n.complementSetCaches[round] = set
+	}
+	return set
+}
+
+// purgeComplementSetCaches should be called when peers are changed.
+func (n *Network) purgeComplementSetCaches() {
+	n.notarySetCachesLock.Lock()
+	defer n.notarySetCachesLock.Unlock()
+	n.complementSetCaches = make(map[uint64]map[types.NodeID]struct{})
+}
+
 func (n *Network) send(endpoint types.NodeID, msg interface{}) {
+	delay := n.config.DirectLatency.Delay()
+	if fake, ok := n.trans.TransportClient.(*FakeTransport); ok &&
+		fake.isStale(delay) {
+		return
+	}
 	go func() {
-		time.Sleep(n.config.DirectLatency.Delay())
+		time.Sleep(delay)
 		if err := n.trans.Send(endpoint, msg); err != nil {
 			panic(err)
 		}
@@ -21,6 +21,7 @@ import (
 	"context"
 	"encoding/json"
 	"math/rand"
+	"net"
 	"sync"
 	"testing"
 	"time"
@@ -84,7 +85,17 @@ func (s *NetworkTestSuite) TestPullRequestMarshaling() {
 	s.Require().NoError(json.Unmarshal(b, req2))
 	s.Require().Equal(req.Requester, req2.Requester)
 	s.Require().Equal(req.Type, req2.Type)
-	s.Require().Equal(blockHashes, req2.Identity)
+	s.Require().ElementsMatch(blockHashes, req2.Identity)
+	// Pull requests of the same set of blocks are marshalled identically.
+	reversed := common.Hashes{blockHashes[2], blockHashes[1], blockHashes[0]}
+	b2, err := json.Marshal(&PullRequest{
+		Requester: req.Requester,
+		Type:      "block",
+		Identity:  reversed,
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(b, b2)
+	s.Require().Equal(blockHashes[2], reversed[0])
 	// Verify pull request for votes is able to be marshalled.
 	req = &PullRequest{
 		Requester: GenerateRandomNodeIDs(1)[0],
@@ -103,6 +114,71 @@ func (s *NetworkTestSuite) TestPullRequestMarshaling() {
 		req.Identity.(types.Position).Round)
 	s.Require().Equal(req.Identity.(types.Position).Height,
 		req.Identity.(types.Position).Height)
+	// Pull requests without version are accepted as version 1.
+	raw := map[string]interface{}{}
+	s.Require().NoError(json.Unmarshal(b, &raw))
+	s.Require().Equal(float64(pullRequestVersion), raw["v"])
+	delete(raw, "v")
+	b, err = json.Marshal(raw)
+	s.Require().NoError(err)
+	req2 = &PullRequest{}
+	s.Require().NoError(json.Unmarshal(b, req2))
+	s.Require().Equal(req.Identity, req2.Identity)
+	// Pull requests from newer versions are rejected.
+	raw["v"] = pullRequestVersion + 1
+	b, err = json.Marshal(raw)
+	s.Require().NoError(err)
+	s.Require().Error(json.Unmarshal(b, &PullRequest{}))
+}
+
+// testBlockHashMarshaller marshals blocks by their hashes only.
+type testBlockHashMarshaller struct {
+	msgType string
+}
+
+func (m *testBlockHashMarshaller) Unmarshal(
+	msgType string, payload []byte) (interface{}, error) {
+	b := &types.Block{}
+	copy(b.Hash[:], payload)
+	return b, nil
+}
+
+func (m *testBlockHashMarshaller) Marshal(
+	msg interface{}) (string, []byte, error) {
+	return m.msgType, msg.(*types.Block).Hash[:], nil
+}
+
+func (s *NetworkTestSuite) TestTypedMarshaller() {
+	req := s.Require()
+	m := NewTypedMarshaller(NewDefaultMarshaller(nil))
+	m.Register(&types.Block{}, "block-hash",
+		&testBlockHashMarshaller{msgType: "block-hash"})
+	// Blocks are handled by the registered marshaller.
+	b := &types.Block{Hash: common.NewRandomHash()}
+	msgType, payload, err := m.Marshal(b)
+	req.NoError(err)
+	req.Equal("block-hash", msgType)
+	req.Len(payload, common.HashLength)
+	msg, err := m.Unmarshal(msgType, payload)
+	req.NoError(err)
+	req.IsType(&types.Block{}, msg)
+	req.Equal(b.Hash, msg.(*types.Block).Hash)
+	// Other messages are handled by the fallback marshaller.
+	v := types.NewVote(types.VoteInit, common.NewRandomHash(), 1)
+	msgType, payload, err = m.Marshal(v)
+	req.NoError(err)
+	req.Equal("vote", msgType)
+	msg, err = m.Unmarshal(msgType, payload)
+	req.NoError(err)
+	req.Equal(v.VoteHeader, msg.(*types.Vote).VoteHeader)
+	// The type returned by registered marshaller should be registered.
+	m = NewTypedMarshaller(nil)
+	m.Register(&types.Block{}, "block-hash",
+		&testBlockHashMarshaller{msgType: "block"})
+	_, _, err = m.Marshal(b)
+	req.Error(err)
+	_, _, err = m.Marshal(v)
+	req.Error(err)
 }
 
 func (s *NetworkTestSuite) TestPullBlocks() {
@@ -115,8 +191,10 @@ func (s *NetworkTestSuite) TestPullBlocks() {
 	networks := s.setupNetworks(pubKeys)
 	// Generate several random hashes.
 	hashes := common.Hashes{}
-	for range networks {
+	for _, n := range networks {
 		hashes = append(hashes, common.NewRandomHash())
+		// Pulls should be split when exceeding MaxBlocksPerPull.
+		n.config.MaxBlocksPerPull = 3
 	}
 	// Randomly pick one network instance as master.
 	var master *Network
@@ -162,6 +240,644 @@ func (s *NetworkTestSuite) TestPullBlocks() {
 	}
 }
 
+func (s *NetworkTestSuite) TestBlockCacheHooks() {
+	var (
+		req  = s.Require()
+		lock sync.Mutex
+	)
+	_, pubKeys, err := NewKeys(2)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	var master, slave *Network
+	for _, n := range networks {
+		if master == nil {
+			master = n
+		} else {
+			slave = n
+		}
+	}
+	// Blocks evicted from cache are notified.
+	evicted := make(map[common.Hash]*types.Block)
+	spilled := &types.Block{Hash: common.NewRandomHash()}
+	slave.SetBlockCacheHooks(func(b *types.Block) {
+		lock.Lock()
+		defer lock.Unlock()
+		evicted[b.Hash] = b
+	}, func(h common.Hash) *types.Block {
+		if h == spilled.Hash {
+			return spilled
+		}
+		return nil
+	})
+	for i := 0; i < maxBlockCache+3; i++ {
+		slave.addBlockToCache(&types.Block{Hash: common.NewRandomHash()})
+	}
+	func() {
+		lock.Lock()
+		defer lock.Unlock()
+		req.Len(evicted, 2)
+		slave.blockCacheLock.RLock()
+		defer slave.blockCacheLock.RUnlock()
+		for h := range evicted {
+			req.NotContains(slave.blockCache, h)
+		}
+	}()
+	// Blocks missed in cache would be served by the hook.
+	master.PullBlocks(common.Hashes{spilled.Hash})
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	for {
+		select {
+		case v := <-master.ReceiveChan():
+			if b, ok := v.Payload.(*types.Block); ok && b.Hash == spilled.Hash {
+				return
+			}
+		case <-ctx.Done():
+			req.FailNow("spilled block is not pulled")
+		}
+	}
+}
+
+func (s *NetworkTestSuite) TestPullRequestLimits() {
+	var req = s.Require()
+	_, pubKeys, err := NewKeys(2)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	master := networks[types.NewNodeID(pubKeys[0])]
+	slave := networks[types.NewNodeID(pubKeys[1])]
+	slave.config.MaxBlocksPerPull = 2
+	slave.config.MaxPullRequestsPerPeer = 1
+	// Make the rate window long enough for the checks below.
+	slave.config.PullRequestRateWindow = 2 * time.Second
+	hashes := common.Hashes{}
+	for i := 0; i < 3; i++ {
+		b := &types.Block{Hash: common.NewRandomHash()}
+		slave.addBlockToCache(b)
+		hashes = append(hashes, b.Hash)
+	}
+	// Only first MaxBlocksPerPull blocks are served.
+	slave.handlePullRequest(master.ID, &PullRequest{
+		Requester: master.ID,
+		Type:      "block",
+		Identity:  hashes,
+	})
+	received := make(map[common.Hash]struct{})
+	timeout := time.After(500 * time.Millisecond)
+Loop:
+	for {
+		select {
+		case v := <-master.ReceiveChan():
+			if b, ok := v.Payload.(*types.Block); ok {
+				received[b.Hash] = struct{}{}
+			}
+		case <-timeout:
+			break Loop
+		}
+	}
+	req.Len(received, 2)
+	req.Contains(received, hashes[0])
+	req.Contains(received, hashes[1])
+	// Requests exceeding the rate are dropped.
+	slave.handlePullRequest(master.ID, &PullRequest{
+		Requester: master.ID,
+		Type:      "block",
+		Identity:  hashes[:1],
+	})
+	req.Equal(uint64(1), slave.Stats().ThrottledPullRequests)
+	// Rates are counted by the sender, not the claimed requester.
+	slave.handlePullRequest(master.ID, &PullRequest{
+		Requester: types.NodeID{Hash: common.NewRandomHash()},
+		Type:      "block",
+		Identity:  hashes[:1],
+	})
+	req.Equal(uint64(2), slave.Stats().ThrottledPullRequests)
+	select {
+	case <-master.ReceiveChan():
+		req.FailNow("throttled pull request is served")
+	case <-time.After(100 * time.Millisecond):
+	}
+	// They are served again after the rate window.
+	time.Sleep(slave.config.PullRequestRateWindow)
+	slave.handlePullRequest(master.ID, &PullRequest{
+		Requester: master.ID,
+		Type:      "block",
+		Identity:  hashes[:1],
+	})
+	req.Equal(uint64(2), slave.Stats().ThrottledPullRequests)
+	// Expired records are pruned.
+	time.Sleep(slave.config.PullRequestRateWindow)
+	slave.handlePullRequest(slave.ID, &PullRequest{
+		Requester: slave.ID,
+		Type:      "vote",
+		Identity:  types.Position{},
+	})
+	func() {
+		slave.pullRequestRatesLock.Lock()
+		defer slave.pullRequestRatesLock.Unlock()
+		req.NotContains(slave.pullRequestRates, master.ID)
+	}()
+}
+
+func (s *NetworkTestSuite) TestPullConcurrencyLimit() {
+	var (
+		req    = s.Require()
+		server = NewFakeTransportServer()
+		wg     sync.WaitGroup
+	)
+	_, pubKeys, err := NewKeys(2)
+	req.NoError(err)
+	serverChannel, err := server.Host()
+	req.NoError(err)
+	// Use a large latency to keep pulling routines busy.
+	var networks []*Network
+	for _, key := range pubKeys {
+		n := NewNetwork(key, NetworkConfig{
+			Type:               NetworkTypeFake,
+			DirectLatency:      &FixedLatencyModel{Latency: 300},
+			GossipLatency:      &FixedLatencyModel{},
+			Marshaller:         NewDefaultMarshaller(nil),
+			MaxConcurrentPulls: 2,
+		})
+		networks = append(networks, n)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req.NoError(n.Setup(serverChannel))
+			go n.Run()
+		}()
+	}
+	req.NoError(server.WaitForPeers(uint32(len(pubKeys))))
+	wg.Wait()
+	n, peer := networks[0], networks[1]
+	censor := &testPullRequestCensor{ch: make(chan *PullRequest, 100)}
+	peer.SetCensor(censor, nil)
+	hashes := make(map[common.Hash]struct{})
+	positions := make(map[types.Position]struct{})
+	for i := 0; i < 5; i++ {
+		h := common.NewRandomHash()
+		pos := types.Position{Height: uint64(i)}
+		hashes[h] = struct{}{}
+		positions[pos] = struct{}{}
+		n.PullBlocks(common.Hashes{h})
+		n.PullVotes(pos)
+	}
+	stats := n.Stats()
+	req.Equal(2, stats.ActivePulls)
+	req.Equal(8, stats.QueuedPulls)
+	// Queued requests should be served by those running routines, wait until
+	// the peer receives pull requests for all of them.
+	timeout := time.After(10 * time.Second)
+	for len(hashes) > 0 || len(positions) > 0 {
+		select {
+		case r := <-censor.ch:
+			switch r.Type {
+			case "block":
+				for _, h := range r.Identity.(common.Hashes) {
+					delete(hashes, h)
+				}
+			case "vote":
+				delete(positions, r.Identity.(types.Position))
+			}
+		case <-timeout:
+			req.FailNow("queued pulls are not served",
+				"blocks: %d, votes: %d", len(hashes), len(positions))
+		}
+	}
+	// Routines would exit once they are done with the last request.
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		stats = n.Stats()
+		if stats.ActivePulls == 0 {
+			break
+		}
+		req.True(time.Now().Before(deadline), "pulling routines don't exit")
+		time.Sleep(10 * time.Millisecond)
+	}
+	req.Equal(0, stats.QueuedPulls)
+}
+
+// testPullRequestCensor reports received pull requests without censoring
+// them.
+type testPullRequestCensor struct {
+	ch chan *PullRequest
+}
+
+func (c *testPullRequestCensor) Censor(msg interface{}) bool {
+	if r, ok := msg.(*PullRequest); ok {
+		c.ch <- r
+	}
+	return false
+}
+
+func (s *NetworkTestSuite) TestAgreementResultRateLimit() {
+	var req = s.Require()
+	_, pubKeys, err := NewKeys(1)
+	req.NoError(err)
+	n := NewNetwork(pubKeys[0], NetworkConfig{
+		Type:                           NetworkTypeFake,
+		DirectLatency:                  &FixedLatencyModel{},
+		GossipLatency:                  &FixedLatencyModel{},
+		MaxAgreementResultsPerPosition: 2,
+	})
+	pos := types.Position{Round: 1, Height: 10}
+	hash := common.NewRandomHash()
+	req.True(n.markAgreementResultAsSent(hash, pos))
+	// Duplicated block hash is not counted as dropped.
+	req.False(n.markAgreementResultAsSent(hash, pos))
+	req.True(n.markAgreementResultAsSent(common.NewRandomHash(), pos))
+	req.False(n.markAgreementResultAsSent(common.NewRandomHash(), pos))
+	req.False(n.markAgreementResultAsSent(common.NewRandomHash(), pos))
+	req.Equal(uint64(2), n.Stats().DroppedAgreementResults)
+	// Other positions are not affected.
+	req.True(n.markAgreementResultAsSent(common.NewRandomHash(),
+		types.Position{Round: 1, Height: 11}))
+	// The limit is lifted after the window.
+	n.agreementRates[pos].since = time.Now().Add(-2 * agreementResultRateWindow)
+	req.True(n.markAgreementResultAsSent(common.NewRandomHash(), pos))
+}
+
+func (s *NetworkTestSuite) TestBlockChunk() {
+	var req = s.Require()
+	_, pubKeys, err := NewKeys(1)
+	req.NoError(err)
+	n := NewNetwork(pubKeys[0], NetworkConfig{
+		Type:           NetworkTypeTCPLocal,
+		DirectLatency:  &FixedLatencyModel{},
+		GossipLatency:  &FixedLatencyModel{},
+		Marshaller:     NewDefaultMarshaller(nil),
+		BlockChunkSize: 10,
+	})
+	b := &types.Block{
+		Hash:     common.NewRandomHash(),
+		Position: types.Position{Round: 1, Height: 2},
+		Payload:  []byte("a payload with length 35 in bytes.."),
+	}
+	// Small blocks are not split.
+	small := &types.Block{Hash: common.NewRandomHash(), Payload: []byte("a")}
+	req.Equal([]interface{}{small}, n.splitBlock(small))
+	msgs := n.splitBlock(b)
+	req.Len(msgs, 4)
+	// Chunks could be marshalled.
+	m := NewDefaultMarshaller(nil)
+	for idx, msg := range msgs {
+		msgType, payload, err := m.Marshal(msg)
+		req.NoError(err)
+		msg, err = m.Unmarshal(msgType, payload)
+		req.NoError(err)
+		msgs[idx] = msg
+	}
+	// Chunks could be assembled in any order, the block is not ready until all
+	// chunks are received.
+	for _, idx := range []int{3, 1, 0} {
+		req.Nil(n.assembleBlockChunk(msgs[idx].(*blockChunk)))
+	}
+	// Duplicated chunks are ignored.
+	req.Nil(n.assembleBlockChunk(msgs[1].(*blockChunk)))
+	assembled := n.assembleBlockChunk(msgs[2].(*blockChunk))
+	req.NotNil(assembled)
+	req.Equal(b.Hash, assembled.Hash)
+	req.Equal(b.Position, assembled.Position)
+	req.Equal(b.Payload, assembled.Payload)
+	req.Empty(n.blockChunks)
+	// Incomplete blocks would be dropped.
+	req.Nil(n.assembleBlockChunk(msgs[0].(*blockChunk)))
+	n.blockChunks[b.Hash].since = time.Now().Add(-2 * blockChunkTimeout)
+	req.Nil(n.assembleBlockChunk(msgs[1].(*blockChunk)))
+	req.Equal(1, n.blockChunks[b.Hash].received)
+}
+
+func (s *NetworkTestSuite) TestBroadcastBlockWithConfirmation() {
+	var (
+		req       = s.Require()
+		peerCount = 5
+	)
+	_, pubKeys, err := NewKeys(peerCount)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	var master *Network
+	for _, master = range networks {
+		break
+	}
+	b := &types.Block{Hash: common.NewRandomHash()}
+	count, err := master.BroadcastBlockWithConfirmation(
+		b, peerCount-1, 3*time.Second)
+	req.NoError(err)
+	req.Equal(peerCount-1, count)
+	// Peers should receive that block.
+	for nID, n := range networks {
+		if nID == master.ID {
+			continue
+		}
+		msg := <-n.ReceiveChan()
+		req.IsType(&types.Block{}, msg.Payload)
+		req.Equal(b.Hash, msg.Payload.(*types.Block).Hash)
+	}
+	// Unable to reach more confirmations than peers.
+	count, err = master.BroadcastBlockWithConfirmation(
+		b, peerCount, 500*time.Millisecond)
+	req.Equal(ErrNotEnoughConfirmation, err)
+	req.Equal(peerCount-1, count)
+	// Receipt requests could be marshalled.
+	m := NewDefaultMarshaller(nil)
+	msgType, payload, err := m.Marshal(&receiptRequest{
+		ID: common.NewRandomHash(), Msg: b})
+	req.NoError(err)
+	msg, err := m.Unmarshal(msgType, payload)
+	req.NoError(err)
+	req.Equal(b.Hash, msg.(*receiptRequest).Msg.(*types.Block).Hash)
+	// So do receipt requests of block chunks.
+	msgType, payload, err = m.Marshal(&receiptRequest{
+		ID: common.NewRandomHash(), Msg: &blockChunk{
+			BlockHash: b.Hash, Total: 2, Data: []byte{1}}})
+	req.NoError(err)
+	msg, err = m.Unmarshal(msgType, payload)
+	req.NoError(err)
+	req.Equal(b.Hash, msg.(*receiptRequest).Msg.(*blockChunk).BlockHash)
+}
+
+func (s *NetworkTestSuite) TestBroadcastBlockWithConfirmationDedup() {
+	var req = s.Require()
+	_, pubKeys, err := NewKeys(2)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	var master, peer *Network
+	for _, n := range networks {
+		if master == nil {
+			master = n
+		} else {
+			peer = n
+		}
+	}
+	// Receipts are injected by the test below.
+	peer.SetCensor(&testDropReceiptRequestCensor{toDrop: 100}, nil)
+	// Split the block into 4 chunks.
+	master.config.Type = NetworkTypeTCPLocal
+	master.config.BlockChunkSize = 10
+	b := &types.Block{
+		Hash:    common.NewRandomHash(),
+		Payload: []byte("a payload with length 35 in bytes.."),
+	}
+	broadcast := func(receiptIDs func(common.Hashes) common.Hashes) (
+		int, error) {
+		type result struct {
+			count int
+			err   error
+		}
+		done := make(chan result, 1)
+		go func() {
+			count, err := master.BroadcastBlockWithConfirmation(
+				b, 1, 500*time.Millisecond)
+			done <- result{count, err}
+		}()
+		var reqIDs common.Hashes
+		deadline := time.Now().Add(time.Second)
+		for len(reqIDs) < 4 {
+			req.True(time.Now().Before(deadline))
+			time.Sleep(10 * time.Millisecond)
+			reqIDs = func() (ids common.Hashes) {
+				master.receiptsLock.Lock()
+				defer master.receiptsLock.Unlock()
+				for id := range master.receipts {
+					ids = append(ids, id)
+				}
+				return
+			}()
+		}
+		for _, id := range receiptIDs(reqIDs) {
+			master.dispatchMsg(&TransportEnvelope{
+				From: peer.ID,
+				Msg:  &receipt{ID: id},
+			})
+		}
+		r := <-done
+		return r.count, r.err
+	}
+	// Duplicated receipts of one chunk don't confirm the whole block.
+	count, err := broadcast(func(ids common.Hashes) common.Hashes {
+		return common.Hashes{ids[0], ids[0], ids[0], ids[0]}
+	})
+	req.Equal(ErrNotEnoughConfirmation, err)
+	req.Equal(0, count)
+	// Receipts of all chunks do.
+	count, err = broadcast(func(ids common.Hashes) common.Hashes {
+		return ids
+	})
+	req.NoError(err)
+	req.Equal(1, count)
+}
+
+type testDropReceiptRequestCensor struct {
+	lock    sync.Mutex
+	toDrop  int
+	dropped int
+}
+
+func (c *testDropReceiptRequestCensor) Censor(msg interface{}) bool {
+	if _, ok := msg.(*receiptRequest); !ok {
+		return false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.dropped < c.toDrop {
+		c.dropped++
+		return true
+	}
+	return false
+}
+
+func (c *testDropReceiptRequestCensor) droppedCount() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.dropped
+}
+
+func (s *NetworkTestSuite) TestSendReliable() {
+	var (
+		req       = s.Require()
+		peerCount = 2
+	)
+	_, pubKeys, err := NewKeys(peerCount)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	var sender, receiver *Network
+	for _, n := range networks {
+		if sender == nil {
+			sender = n
+		} else {
+			receiver = n
+		}
+	}
+	// The first attempt is lost, the retry should be confirmed.
+	censor := &testDropReceiptRequestCensor{toDrop: 1}
+	receiver.SetCensor(censor, nil)
+	prvShare := &typesDKG.PrivateShare{Round: 1}
+	req.NoError(sender.SendReliable(
+		receiver.ID, prvShare, 200*time.Millisecond))
+	msg := <-receiver.ReceiveChan()
+	req.IsType(&typesDKG.PrivateShare{}, msg.Payload)
+	req.Equal(1, censor.droppedCount())
+	// All attempts are lost.
+	sender.config.MaxSendRetries = 1
+	censor = &testDropReceiptRequestCensor{toDrop: 100}
+	receiver.SetCensor(censor, nil)
+	req.Equal(ErrSendNotConfirmed, sender.SendReliable(
+		receiver.ID, prvShare, 200*time.Millisecond))
+	req.Equal(2, censor.droppedCount())
+}
+
+func (s *NetworkTestSuite) TestSentMarkerExpiration() {
+	var req = s.Require()
+	_, pubKeys, err := NewKeys(1)
+	req.NoError(err)
+	n := NewNetwork(pubKeys[0], NetworkConfig{
+		Type:          NetworkTypeFake,
+		DirectLatency: &FixedLatencyModel{},
+		GossipLatency: &FixedLatencyModel{},
+		SentMarkerTTL: time.Second,
+	})
+	hash := common.NewRandomHash()
+	req.True(n.markAgreementResultAsSent(hash, types.Position{Height: 1}))
+	req.False(n.markAgreementResultAsSent(hash, types.Position{Height: 1}))
+	// Not expired yet.
+	n.purgeExpiredSentMarkers(time.Now())
+	req.Contains(n.sentAgreement, hash)
+	n.purgeExpiredSentMarkers(time.Now().Add(2 * time.Second))
+	req.NotContains(n.sentAgreement, hash)
+	req.Empty(n.SentMarkersSnapshot())
+	req.True(n.markAgreementResultAsSent(hash, types.Position{Height: 1}))
+	// Gossiping an evicted agreement result again is detected.
+	req.Equal(uint64(1), n.Stats().RemarkedAgreementResults)
+	req.Equal(common.Hashes{hash}, n.SentMarkersSnapshot())
+	// The oldest marker is dropped once the cache is full.
+	n.sentAgreement[hash] = time.Now().Add(-time.Second)
+	for i := 0; i < maxSentAgreementCache; i++ {
+		req.True(n.markAgreementResultAsSent(
+			common.NewRandomHash(), types.Position{Height: uint64(i + 2)}))
+	}
+	req.Len(n.sentAgreement, maxSentAgreementCache)
+	req.NotContains(n.sentAgreement, hash)
+}
+
+func (s *NetworkTestSuite) TestPeerScore() {
+	var req = s.Require()
+	_, pubKeys, err := NewKeys(4)
+	req.NoError(err)
+	n := NewNetwork(pubKeys[0], NetworkConfig{
+		Type:          NetworkTypeFake,
+		DirectLatency: &FixedLatencyModel{},
+		GossipLatency: &FixedLatencyModel{},
+	})
+	peers := make(map[types.NodeID]struct{})
+	for _, key := range pubKeys {
+		peers[types.NewNodeID(key)] = struct{}{}
+	}
+	good := types.NewNodeID(pubKeys[1])
+	bad := types.NewNodeID(pubKeys[2])
+	n.updatePeerScore(good, peerScoreReward)
+	n.updatePeerScore(bad, -peerScorePenalty)
+	// Self should be excluded, and the responsive peer should be preferred
+	// in most cases.
+	goodFirst := 0
+	for i := 0; i < 1000; i++ {
+		ranked := n.rankPeers(peers)
+		req.Len(ranked, 3)
+		req.NotContains(ranked, n.ID)
+		if ranked[0] == good {
+			goodFirst++
+		}
+	}
+	req.True(goodFirst > 800)
+	// Scores decay over time.
+	n.peerScores[good].updated = time.Now().Add(-peerScoreHalfLife)
+	stats := n.Stats()
+	req.InDelta(peerScoreReward/2, stats.PeerScores[good], 0.01)
+	req.True(stats.PeerScores[bad] < 0)
+}
+
+func (s *NetworkTestSuite) TestVotePositionWindow() {
+	var (
+		req    = s.Require()
+		window = 8
+	)
+	_, pubKeys, err := NewKeys(1)
+	req.NoError(err)
+	n := NewNetwork(pubKeys[0], NetworkConfig{
+		Type:               NetworkTypeFake,
+		DirectLatency:      &FixedLatencyModel{},
+		GossipLatency:      &FixedLatencyModel{},
+		Marshaller:         NewDefaultMarshaller(nil),
+		VotePositionWindow: window,
+	})
+	for h := uint64(0); h < 10000; h++ {
+		pos := types.Position{Height: h}
+		for i := 0; i < 2; i++ {
+			v := types.NewVote(types.VoteInit, common.NewRandomHash(), 0)
+			v.Position = pos
+			n.addVoteToCache(v)
+		}
+		req.True(len(n.votePositions) <= window)
+		req.True(cap(n.votePositions) <= 2*window)
+		req.Len(n.voteCache, len(n.votePositions))
+	}
+	// Only votes of latest positions are kept.
+	req.Equal(types.Position{Height: 9999},
+		n.votePositions[len(n.votePositions)-1])
+	req.Equal(2*window, n.voteCacheSize)
+}
+
+func (s *NetworkTestSuite) TestExportImportVoteCache() {
+	var req = s.Require()
+	_, pubKeys, err := NewKeys(2)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	master := networks[types.NewNodeID(pubKeys[0])]
+	for h := uint64(0); h < 2*maxVoteCache; h++ {
+		v := types.NewVote(types.VoteInit, common.NewRandomHash(), 0)
+		v.Position = types.Position{Height: h}
+		master.addVoteToCache(v)
+	}
+	votes := make(map[types.VoteHeader]struct{})
+	pos := types.Position{Height: 2 * maxVoteCache}
+	for i := 0; i < 3; i++ {
+		v := types.NewVote(types.VoteInit, common.NewRandomHash(), 0)
+		v.Position = pos
+		master.addVoteToCache(v)
+		votes[v.VoteHeader] = struct{}{}
+	}
+	data, err := master.ExportVoteCache()
+	req.NoError(err)
+	snapshot := voteCacheSnapshot{}
+	req.NoError(json.Unmarshal(data, &snapshot))
+	req.True(len(snapshot.Votes) <= maxVoteCache)
+	// Recreate networks and import the vote cache.
+	networks = s.setupNetworks(pubKeys)
+	master = networks[types.NewNodeID(pubKeys[0])]
+	slave := networks[types.NewNodeID(pubKeys[1])]
+	req.NoError(master.ImportVoteCache(data))
+	req.Equal(len(snapshot.Votes), master.voteCacheSize)
+	master.handlePullRequest(slave.ID, &PullRequest{
+		Requester: slave.ID,
+		Type:      "vote",
+		Identity:  pos,
+	})
+	for len(votes) > 0 {
+		select {
+		case msg := <-slave.ReceiveChan():
+			v, ok := msg.Payload.(*types.Vote)
+			req.True(ok)
+			req.Contains(votes, v.VoteHeader)
+			delete(votes, v.VoteHeader)
+		case <-time.After(time.Second):
+			req.FailNow("votes are not served after importing")
+		}
+	}
+	// Formats of other versions are rejected.
+	snapshot.Version = voteCacheSnapshotVersion + 1
+	data, err = json.Marshal(&snapshot)
+	req.NoError(err)
+	req.Equal(ErrVoteCacheVersionMismatch, master.ImportVoteCache(data))
+}
+
 func (s *NetworkTestSuite) TestPullVotes() {
 	var (
 		peerCount     = maxPullingPeerCount
@@ -294,6 +1010,100 @@ func (s *NetworkTestSuite) TestBroadcastToSet() {
 	nerd.BroadcastBlock(&types.Block{Position: pos})
 	msg = <-notaryNode.ReceiveChan()
 	req.IsType(&types.Block{}, msg.Payload)
+	// Purging node set cache of that round would purge cached sets as well.
+	req.Len(nerd.getNotarySetComplement(round), peerCount-1)
+	nerd.PurgeNodeSetCache(round)
+	req.NotContains(nerd.notarySetCaches, round)
+	req.NotContains(nerd.complementSetCaches, round)
+}
+
+func (s *NetworkTestSuite) TestSetPeers() {
+	var (
+		req    = s.Require()
+		server = NewFakeTransportServer()
+		wg     sync.WaitGroup
+	)
+	_, pubKeys, err := NewKeys(3)
+	req.NoError(err)
+	serverChannel, err := server.Host()
+	req.NoError(err)
+	networks := make([]*Network, 0, len(pubKeys))
+	for _, key := range pubKeys {
+		networks = append(networks, NewNetwork(key, NetworkConfig{
+			Type:          NetworkTypeFake,
+			DirectLatency: &FixedLatencyModel{},
+			GossipLatency: &FixedLatencyModel{},
+			Marshaller:    NewDefaultMarshaller(nil)}))
+	}
+	// Only the second node is known by the first one.
+	networks[0].SetPeers(pubKeys[1:2])
+	for _, n := range networks {
+		wg.Add(1)
+		go func(n *Network) {
+			defer wg.Done()
+			req.NoError(n.Setup(serverChannel))
+			go n.Run()
+		}(n)
+	}
+	req.NoError(server.WaitForPeers(uint32(len(pubKeys))))
+	wg.Wait()
+	req.Len(networks[0].peers, 1)
+	req.Contains(networks[0].peers, networks[1].ID)
+	networks[0].BroadcastVote(&types.Vote{})
+	msg := <-networks[1].ReceiveChan()
+	req.IsType(&types.Vote{}, msg.Payload)
+	time.Sleep(50 * time.Millisecond)
+	req.Len(networks[2].ReceiveChan(), 0)
+}
+
+func (s *NetworkTestSuite) TestDisableGossip() {
+	var (
+		req       = s.Require()
+		peerCount = 5
+		round     = uint64(1)
+	)
+	_, pubKeys, err := NewKeys(peerCount)
+	req.NoError(err)
+	gov, err := NewGovernance(NewState(
+		1, pubKeys, time.Second, &common.NullLogger{}, true), 2)
+	req.NoError(err)
+	req.NoError(gov.State().RequestChange(StateChangeNotarySetSize, uint32(1)))
+	gov.NotifyRound(round,
+		utils.GetRoundHeight(gov, 0)+gov.Configuration(0).RoundLength)
+	networks := s.setupNetworks(pubKeys)
+	cache := utils.NewNodeSetCache(gov)
+	notarySet, err := cache.GetNotarySet(round)
+	req.NoError(err)
+	var sender, other, notaryNode *Network
+	for nID, n := range networks {
+		if _, exists := notarySet[nID]; exists {
+			notaryNode = n
+		} else if sender == nil {
+			sender = n
+		} else if other == nil {
+			other = n
+		}
+	}
+	req.NotNil(sender)
+	req.NotNil(other)
+	req.NotNil(notaryNode)
+	sender.AttachNodeSetCache(cache)
+	sender.config.DisableGossip = true
+	pos := types.Position{Round: round, Height: types.GenesisHeight}
+	b := &types.Block{Position: pos, Hash: common.NewRandomHash()}
+	sender.BroadcastBlock(b)
+	msg := <-notaryNode.ReceiveChan()
+	req.IsType(&types.Block{}, msg.Payload)
+	sender.BroadcastAgreementResult(&types.AgreementResult{
+		BlockHash: b.Hash, Position: pos})
+	msg = <-notaryNode.ReceiveChan()
+	req.IsType(&types.AgreementResult{}, msg.Payload)
+	// Nodes not in notary set should receive nothing.
+	select {
+	case msg := <-other.ReceiveChan():
+		req.FailNow("unexpected message", "%v", msg.Payload)
+	case <-time.After(200 * time.Millisecond):
+	}
 }
 
 type testVoteCensor struct{}
@@ -374,6 +1184,341 @@ func (s *NetworkTestSuite) TestCensor() {
 
 }
 
+func (s *NetworkTestSuite) TestPeerReachable() {
+	var (
+		req       = s.Require()
+		peerCount = 3
+	)
+	_, pubKeys, err := NewKeys(peerCount)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	sender := networks[types.NewNodeID(pubKeys[0])]
+	offline := networks[types.NewNodeID(pubKeys[1])]
+	online := networks[types.NewNodeID(pubKeys[2])]
+	sender.SetPeerReachable(offline.ID, false)
+	sender.BroadcastVote(&types.Vote{})
+	msg := <-online.ReceiveChan()
+	req.IsType(&types.Vote{}, msg.Payload)
+	sender.send(offline.ID, &types.Vote{})
+	time.Sleep(50 * time.Millisecond)
+	req.Len(offline.ReceiveChan(), 0)
+	req.Equal(uint64(2), sender.Stats().DroppedUnreachableMsgs)
+	// Messages are delivered again once the peer is reachable.
+	sender.SetPeerReachable(offline.ID, true)
+	sender.BroadcastVote(&types.Vote{})
+	msg = <-offline.ReceiveChan()
+	req.IsType(&types.Vote{}, msg.Payload)
+	req.Equal(uint64(2), sender.Stats().DroppedUnreachableMsgs)
+}
+
+func (s *NetworkTestSuite) TestUnhandledMsgPolicy() {
+	var req = s.Require()
+	_, pubKeys, err := NewKeys(2)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	sender := networks[types.NewNodeID(pubKeys[0])]
+	receiver := networks[types.NewNodeID(pubKeys[1])]
+	// Packed state changes are not handled without state attached.
+	receiver.config.UnhandledMsgPolicy = UnhandledMsgReport
+	req.NoError(sender.trans.Send(receiver.ID, packedStateChanges{}))
+	select {
+	case err := <-receiver.UnhandledMsgErrChan():
+		req.Contains(err.Error(), ErrUnhandledMessage.Error())
+	case <-time.After(time.Second):
+		req.FailNow("unhandled message is not reported")
+	}
+	receiver.config.UnhandledMsgPolicy = UnhandledMsgDrop
+	req.NoError(sender.trans.Send(receiver.ID, packedStateChanges{}))
+	time.Sleep(50 * time.Millisecond)
+	req.Len(receiver.UnhandledMsgErrChan(), 0)
+	req.Len(receiver.ReceiveChanForNode(), 0)
+	req.Equal(uint64(2), receiver.Stats().DroppedUnhandledMsgs)
+	// Forward them to node by default.
+	receiver.config.UnhandledMsgPolicy = UnhandledMsgForward
+	req.NoError(sender.trans.Send(receiver.ID, packedStateChanges{}))
+	select {
+	case msg := <-receiver.ReceiveChanForNode():
+		req.IsType(packedStateChanges{}, msg)
+	case <-time.After(time.Second):
+		req.FailNow("unhandled message is not forwarded")
+	}
+}
+
+func (s *NetworkTestSuite) TestDropWhenBusy() {
+	var req = s.Require()
+	_, pubKeys, err := NewKeys(2)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	sender := networks[types.NewNodeID(pubKeys[0])]
+	receiver := networks[types.NewNodeID(pubKeys[1])]
+	// Nobody reads from receiver, dispatching routines would be blocked once
+	// the channel to consensus is full, and the rest are dropped.
+	count := cap(receiver.toConsensus) + receiver.config.DispatchWorkers +
+		dispatchQueueSize + 100
+	for i := 0; i < count; i++ {
+		req.NoError(sender.trans.Send(receiver.ID, &types.Vote{}))
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for receiver.Stats().DroppedBusyMsgs == 0 {
+		req.True(time.Now().Before(deadline), "busy messages are not dropped")
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Reading from transport layer is not blocked.
+Drain:
+	for {
+		select {
+		case <-receiver.ReceiveChan():
+		case <-time.After(100 * time.Millisecond):
+			break Drain
+		}
+	}
+	req.NoError(sender.trans.Send(receiver.ID, &appMessage{Payload: []byte{1}}))
+	select {
+	case payload := <-receiver.AppMessageChan():
+		req.Equal([]byte{1}, payload)
+	case <-time.After(time.Second):
+		req.FailNow("messages are not dispatched after being busy")
+	}
+}
+
+func (s *NetworkTestSuite) TestMaxMessageAge() {
+	var req = s.Require()
+	_, pubKeys, err := NewKeys(2)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	sender := networks[types.NewNodeID(pubKeys[0])]
+	receiver := networks[types.NewNodeID(pubKeys[1])]
+	sender.trans.TransportClient.(*FakeTransport).SetMaxMessageAge(
+		50 * time.Millisecond)
+	// Messages delayed longer than maximum message age are dropped.
+	sender.config.DirectLatency = &FixedLatencyModel{Latency: 100}
+	stale := &types.Block{Hash: common.NewRandomHash()}
+	sender.send(receiver.ID, stale)
+	req.NoError(sender.trans.Broadcast(
+		map[types.NodeID]struct{}{receiver.ID: {}},
+		&FixedLatencyModel{Latency: 100}, stale))
+	req.Equal(uint64(2), sender.Stats().DroppedStaleMsgs)
+	// Those within maximum message age are delivered.
+	sender.config.DirectLatency = &FixedLatencyModel{Latency: 10}
+	fresh := &types.Block{Hash: common.NewRandomHash()}
+	sender.send(receiver.ID, fresh)
+	select {
+	case v := <-receiver.ReceiveChan():
+		b, ok := v.Payload.(*types.Block)
+		req.True(ok)
+		req.Equal(fresh.Hash, b.Hash)
+	case <-time.After(time.Second):
+		req.FailNow("fresh message is not delivered")
+	}
+	req.Equal(uint64(2), sender.Stats().DroppedStaleMsgs)
+}
+
+func (s *NetworkTestSuite) TestAgreementResultRoundWindow() {
+	var req = s.Require()
+	_, pubKeys, err := NewKeys(2)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	sender := networks[types.NewNodeID(pubKeys[0])]
+	receiver := networks[types.NewNodeID(pubKeys[1])]
+	receiver.config.AgreementResultRoundWindow = 2
+	// Nothing is dropped before the current round is known.
+	receiver.dispatchMsg(&TransportEnvelope{
+		From: sender.ID,
+		Msg: &types.AgreementResult{
+			BlockHash: common.NewRandomHash(),
+			Position:  types.Position{Round: 100},
+		},
+	})
+	req.Len(receiver.ReceiveChan(), 1)
+	<-receiver.ReceiveChan()
+	receiver.SetCurrentRound(5)
+	for _, round := range []uint64{1, 2, 3, 5, 7, 8, 100} {
+		receiver.dispatchMsg(&TransportEnvelope{
+			From: sender.ID,
+			Msg: &types.AgreementResult{
+				BlockHash: common.NewRandomHash(),
+				Position:  types.Position{Round: round},
+			},
+		})
+	}
+	// Only results of round 3, 5, 7 are forwarded.
+	req.Len(receiver.ReceiveChan(), 3)
+	for _, round := range []uint64{3, 5, 7} {
+		msg := <-receiver.ReceiveChan()
+		result, ok := msg.Payload.(*types.AgreementResult)
+		req.True(ok)
+		req.Equal(round, result.Position.Round)
+	}
+	req.Equal(uint64(4), receiver.Stats().ImplausibleAgreementResults)
+}
+
+func (s *NetworkTestSuite) TestAppMessage() {
+	var req = s.Require()
+	_, pubKeys, err := NewKeys(3)
+	req.NoError(err)
+	networks := s.setupNetworks(pubKeys)
+	sender := networks[types.NewNodeID(pubKeys[0])]
+	payload := []byte("app message")
+	req.NoError(sender.BroadcastAppMessage(payload))
+	for nID, n := range networks {
+		if nID == sender.ID {
+			continue
+		}
+		select {
+		case msg := <-n.AppMessageChan():
+			req.Equal(payload, msg)
+		case <-time.After(time.Second):
+			req.FailNow("app message is not received")
+		}
+		// Not forwarded to consensus or node.
+		req.Len(n.ReceiveChan(), 0)
+		req.Len(n.ReceiveChanForNode(), 0)
+	}
+	// App messages could be marshalled.
+	m := NewDefaultMarshaller(nil)
+	msgType, raw, err := m.Marshal(&appMessage{Payload: payload})
+	req.NoError(err)
+	msg, err := m.Unmarshal(msgType, raw)
+	req.NoError(err)
+	req.Equal(payload, msg.(*appMessage).Payload)
+	// App messages are dropped when nobody reads them, other messages are
+	// not blocked.
+	receiver := networks[types.NewNodeID(pubKeys[1])]
+	for i := 0; i <= cap(receiver.toApp); i++ {
+		req.NoError(
+			sender.trans.Send(receiver.ID, &appMessage{Payload: payload}))
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for receiver.Stats().DroppedAppMsgs == 0 {
+		req.True(time.Now().Before(deadline), "app messages are not dropped")
+		time.Sleep(10 * time.Millisecond)
+	}
+	req.NoError(sender.trans.Send(receiver.ID, &types.Vote{}))
+	select {
+	case msg := <-receiver.ReceiveChan():
+		req.IsType(&types.Vote{}, msg.Payload)
+	case <-time.After(time.Second):
+		req.FailNow("vote is blocked by app messages")
+	}
+}
+
+func (s *NetworkTestSuite) TestJoinTimeout() {
+	var req = s.Require()
+	// A peer server accepting connections but never responding.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	req.NoError(err)
+	defer ln.Close()
+	_, pubKeys, err := NewKeys(1)
+	req.NoError(err)
+	n := NewNetwork(pubKeys[0], NetworkConfig{
+		Type:        NetworkTypeTCPLocal,
+		PeerServer:  "127.0.0.1",
+		PeerPort:    ln.Addr().(*net.TCPAddr).Port,
+		Marshaller:  NewDefaultMarshaller(nil),
+		JoinTimeout: 500 * time.Millisecond,
+	})
+	begin := time.Now()
+	err = n.Setup(nil)
+	req.Error(err)
+	req.Contains(err.Error(), ErrJoinTimeout.Error())
+	// The handshake should be cancelled instead of waiting for its own
+	// deadline.
+	req.True(time.Since(begin) < 2*time.Second)
+	// The transport is closed only after Join returned.
+	req.Nil(n.trans.TransportClient.(*TCPTransportClient).recvChannel)
+}
+
 func TestNetwork(t *testing.T) {
 	suite.Run(t, new(NetworkTestSuite))
 }
+
+func benchmarkComplementSet(b *testing.B, cached bool) {
+	_, pubKeys, err := NewKeys(100)
+	if err != nil {
+		b.Fatal(err)
+	}
+	gov, err := NewGovernance(NewState(
+		1, pubKeys, time.Second, &common.NullLogger{}, true), 2)
+	if err != nil {
+		b.Fatal(err)
+	}
+	n := NewNetwork(pubKeys[0], NetworkConfig{Type: NetworkTypeFake})
+	n.AttachNodeSetCache(utils.NewNodeSetCache(gov))
+	notarySet := make(map[types.NodeID]struct{})
+	for i, key := range pubKeys {
+		nID := types.NewNodeID(key)
+		n.peers[nID] = struct{}{}
+		if i < 20 {
+			notarySet[nID] = struct{}{}
+		}
+	}
+	n.notarySetCaches[0] = notarySet
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if cached {
+			n.getNotarySetComplement(0)
+		} else {
+			getComplementSet(n.peers, n.getNotarySet(0))
+		}
+	}
+}
+
+func BenchmarkComplementSet(b *testing.B) {
+	benchmarkComplementSet(b, false)
+}
+
+func BenchmarkComplementSetCached(b *testing.B) {
+	benchmarkComplementSet(b, true)
+}
+
+func BenchmarkDispatchMsg(b *testing.B) {
+	_, pubKeys, err := NewKeys(2)
+	if err != nil {
+		b.Fatal(err)
+	}
+	server := NewFakeTransportServer()
+	serverChannel, err := server.Host()
+	if err != nil {
+		b.Fatal(err)
+	}
+	networks := make([]*Network, 0, len(pubKeys))
+	errs := make(chan error, len(pubKeys))
+	for _, key := range pubKeys {
+		n := NewNetwork(key, NetworkConfig{
+			Type:          NetworkTypeFake,
+			DirectLatency: &FixedLatencyModel{},
+			GossipLatency: &FixedLatencyModel{},
+			Marshaller:    NewDefaultMarshaller(nil)})
+		networks = append(networks, n)
+		go func() {
+			errs <- n.Setup(serverChannel)
+		}()
+	}
+	if err = server.WaitForPeers(uint32(len(pubKeys))); err != nil {
+		b.Fatal(err)
+	}
+	for range networks {
+		if err = <-errs; err != nil {
+			b.Fatal(err)
+		}
+	}
+	for _, n := range networks {
+		go n.Run()
+	}
+	sender, receiver := networks[0], networks[1]
+	b.ReportAllocs()
+	b.ResetTimer()
+	go func() {
+		for i := 0; i < b.N; i++ {
+			vote := &types.Vote{}
+			vote.Position.Height = uint64(i)
+			if err := sender.trans.Send(receiver.ID, vote); err != nil {
+				panic(err)
+			}
+		}
+	}()
+	for i := 0; i < b.N; i++ {
+		<-receiver.ReceiveChan()
+	}
+}
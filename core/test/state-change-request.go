@@ -50,6 +50,9 @@ const (
 	StateChangeNotarySetSize
 	// Node set related.
 	StateAddNode
+	// Configuration related, appended to keep values of existing types.
+	StateChangeMaxClockSkew
+	StateChangeMaxBlockPayloadSize
 )
 
 func (t StateChangeType) String() string {
@@ -78,6 +81,10 @@ func (t StateChangeType) String() string {
 		return "ChangeNotarySetSize"
 	case StateAddNode:
 		return "AddNode"
+	case StateChangeMaxClockSkew:
+		return "ChangeMaxClockSkew"
+	case StateChangeMaxBlockPayloadSize:
+		return "ChangeMaxBlockPayloadSize"
 	}
 	panic(fmt.Errorf("attempting to dump unknown type of state change: %d", t))
 }
@@ -193,6 +200,10 @@ func (req *StateChangeRequest) String() (ret string) {
 		ret += fmt.Sprintf("%v", time.Duration(req.Payload.(uint64)))
 	case StateChangeNotarySetSize:
 		ret += fmt.Sprintf("%v", req.Payload.(uint32))
+	case StateChangeMaxClockSkew:
+		ret += fmt.Sprintf("%v", time.Duration(req.Payload.(uint64)))
+	case StateChangeMaxBlockPayloadSize:
+		ret += fmt.Sprintf("%v", req.Payload.(uint64))
 	case StateAddNode:
 		ret += fmt.Sprintf(
 			"%s", types.NewNodeID(req.Payload.(crypto.PublicKey)).String()[:6])
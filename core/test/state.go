@@ -99,6 +99,8 @@ type State struct {
 	notarySetSize    uint32
 	roundInterval    uint64
 	minBlockInterval time.Duration
+	maxClockSkew     time.Duration
+	maxPayloadSize   uint64
 	// Nodes
 	nodes map[types.NodeID]crypto.PublicKey
 	// DKG & CRS
@@ -184,11 +186,13 @@ func (s *State) Snapshot() (*types.Config, []crypto.PublicKey) {
 		nodes = append(nodes, key)
 	}
 	cfg := &types.Config{
-		LambdaBA:         s.lambdaBA,
-		LambdaDKG:        s.lambdaDKG,
-		NotarySetSize:    s.notarySetSize,
-		RoundLength:      s.roundInterval,
-		MinBlockInterval: s.minBlockInterval,
+		LambdaBA:            s.lambdaBA,
+		LambdaDKG:           s.lambdaDKG,
+		NotarySetSize:       s.notarySetSize,
+		RoundLength:         s.roundInterval,
+		MinBlockInterval:    s.minBlockInterval,
+		MaxClockSkew:        s.maxClockSkew,
+		MaxBlockPayloadSize: s.maxPayloadSize,
 	}
 	s.logger.Info("Snapshot config", "config", cfg)
 	return cfg, nodes
@@ -244,6 +248,14 @@ func (s *State) unpackPayload(
 		var tmp uint32
 		err = rlp.DecodeBytes(raw.Payload, &tmp)
 		v = tmp
+	case StateChangeMaxClockSkew:
+		var tmp uint64
+		err = rlp.DecodeBytes(raw.Payload, &tmp)
+		v = tmp
+	case StateChangeMaxBlockPayloadSize:
+		var tmp uint64
+		err = rlp.DecodeBytes(raw.Payload, &tmp)
+		v = tmp
 	case StateAddNode:
 		var tmp []byte
 		err = rlp.DecodeBytes(raw.Payload, &tmp)
@@ -286,7 +298,9 @@ func (s *State) Equal(other *State) error {
 		s.lambdaDKG == other.lambdaDKG &&
 		s.notarySetSize == other.notarySetSize &&
 		s.roundInterval == other.roundInterval &&
-		s.minBlockInterval == other.minBlockInterval
+		s.minBlockInterval == other.minBlockInterval &&
+		s.maxClockSkew == other.maxClockSkew &&
+		s.maxPayloadSize == other.maxPayloadSize
 	if !configEqual {
 		return ErrStateConfigNotEqual
 	}
@@ -477,6 +491,8 @@ func (s *State) Clone() (copied *State) {
 		notarySetSize:    s.notarySetSize,
 		roundInterval:    s.roundInterval,
 		minBlockInterval: s.minBlockInterval,
+		maxClockSkew:     s.maxClockSkew,
+		maxPayloadSize:   s.maxPayloadSize,
 		local:            s.local,
 		logger:           s.logger,
 		nodes:            make(map[types.NodeID]crypto.PublicKey),
@@ -816,6 +832,10 @@ func (s *State) applyRequest(req *StateChangeRequest) error {
 		s.minBlockInterval = time.Duration(req.Payload.(uint64))
 	case StateChangeNotarySetSize:
 		s.notarySetSize = req.Payload.(uint32)
+	case StateChangeMaxClockSkew:
+		s.maxClockSkew = time.Duration(req.Payload.(uint64))
+	case StateChangeMaxBlockPayloadSize:
+		s.maxPayloadSize = req.Payload.(uint64)
 	default:
 		return errors.New("you are definitely kidding me")
 	}
@@ -841,7 +861,8 @@ func (s *State) RequestChange(
 		payload = payload.(crypto.PublicKey).Bytes()
 	case StateChangeLambdaBA,
 		StateChangeLambdaDKG,
-		StateChangeMinBlockInterval:
+		StateChangeMinBlockInterval,
+		StateChangeMaxClockSkew:
 		payload = uint64(payload.(time.Duration))
 	// These cases for for type assertion, make sure callers pass expected types.
 	case StateAddCRS:
@@ -147,6 +147,8 @@ func (s *StateTestSuite) makeConfigChanges(st *State) {
 	st.RequestChange(StateChangeRoundLength, uint64(1001))
 	st.RequestChange(StateChangeMinBlockInterval, time.Second)
 	st.RequestChange(StateChangeNotarySetSize, uint32(5))
+	st.RequestChange(StateChangeMaxClockSkew, 3*time.Second)
+	st.RequestChange(StateChangeMaxBlockPayloadSize, uint64(1024))
 }
 
 func (s *StateTestSuite) checkConfigChanges(config *types.Config) {
@@ -156,6 +158,8 @@ func (s *StateTestSuite) checkConfigChanges(config *types.Config) {
 	req.Equal(config.RoundLength, uint64(1001))
 	req.Equal(config.MinBlockInterval, time.Second)
 	req.Equal(config.NotarySetSize, uint32(5))
+	req.Equal(config.MaxClockSkew, 3*time.Second)
+	req.Equal(config.MaxBlockPayloadSize, uint64(1024))
 }
 
 func (s *StateTestSuite) TestEqual() {
@@ -624,17 +624,27 @@ func (t *TCPTransportClient) Report(msg interface{}) (err error) {
 }
 
 // Join implements TransportClient.Join method.
-func (t *TCPTransportClient) Join(
+func (t *TCPTransportClient) Join(ctx context.Context,
 	serverEndpoint interface{}) (ch <-chan *TransportEnvelope, err error) {
 	// Initiate a TCP server.
 	// TODO(mission): config initial listening port.
 	var (
-		ln        net.Listener
-		envelopes = []*TransportEnvelope{}
-		ok        bool
-		addr      string
-		conn      string
+		ln         net.Listener
+		envelopes  = []*TransportEnvelope{}
+		ok         bool
+		addr       string
+		conn       string
+		serverConn net.Conn
+		writing    bool
 	)
+	defer func() {
+		// Once the writer routine is raised, the connection to server would
+		// be closed by it when this transport is closed.
+		if err != nil && serverConn != nil && !writing {
+			// #nosec G104
+			serverConn.Close()
+		}
+	}()
 	for {
 		addr = net.JoinHostPort("0.0.0.0", strconv.Itoa(t.localPort))
 		ln, err = net.Listen("tcp", addr)
@@ -684,15 +694,33 @@ func (t *TCPTransportClient) Join(
 	}
 
 	fmt.Println("Connecting to server", "endpoint", serverEndpoint)
-	serverConn, err := net.Dial("tcp", serverEndpoint.(string))
+	var dialer net.Dialer
+	serverConn, err = dialer.DialContext(ctx, "tcp", serverEndpoint.(string))
 	if err != nil {
 		return
 	}
+	// Unblock the handshake once ctx is done.
+	handshakeDone, watcherDone := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(watcherDone)
+		select {
+		case <-ctx.Done():
+			// #nosec G104
+			serverConn.SetDeadline(time.Now())
+		case <-handshakeDone:
+		}
+	}()
 	_, err = t.clientHandshake(serverConn)
+	close(handshakeDone)
+	<-watcherDone
 	if err != nil {
 		return
 	}
+	if err = ctx.Err(); err != nil {
+		return
+	}
 	t.serverWriteChannel = t.connWriter(serverConn)
+	writing = true
 	if t.local {
 		conn = addr
 	} else {
@@ -711,7 +739,13 @@ func (t *TCPTransportClient) Join(
 		return
 	}
 	// Wait for peers list sent by server.
-	e := <-t.recvChannel
+	var e *TransportEnvelope
+	select {
+	case e = <-t.recvChannel:
+	case <-ctx.Done():
+		err = ctx.Err()
+		return
+	}
 	handshake, ok := e.Msg.(*tcpHandshake)
 	if !ok {
 		panic(fmt.Errorf("expect handshake, not %v", e))
@@ -738,7 +772,12 @@ func (t *TCPTransportClient) Join(
 	}
 	// Wait for server to ack us that all peers are ready.
 	for {
-		e := <-t.recvChannel
+		select {
+		case e = <-t.recvChannel:
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		}
 		msg, ok := e.Msg.(*tcpMessage)
 		if !ok {
 			envelopes = append(envelopes, e)
@@ -18,6 +18,7 @@
 package test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -219,7 +220,7 @@ func (s *TransportTestSuite) TestFake() {
 		peers[nID] = peer
 		go func() {
 			defer wg.Done()
-			recv, err := peer.trans.Join(server.recv)
+			recv, err := peer.trans.Join(context.Background(), server.recv)
 			req.Nil(err)
 			peer.recv = recv
 		}()
@@ -265,7 +266,7 @@ func (s *TransportTestSuite) TestTCPLocal() {
 		go func() {
 			defer wg.Done()
 
-			recv, err := peer.trans.Join(serverAddr)
+			recv, err := peer.trans.Join(context.Background(), serverAddr)
 			req.Nil(err)
 			peer.recv = recv
 		}()
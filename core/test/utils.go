@@ -18,6 +18,7 @@
 package test
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"math"
@@ -42,6 +43,14 @@ func GenerateRandomNodeIDs(nodeCount int) (nIDs types.NodeIDs) {
 	return
 }
 
+// DeterministicCRS derives the CRS of a round from a seed, the same seed
+// always leads to the same sequence of CRS.
+func DeterministicCRS(seed []byte, round uint64) common.Hash {
+	binaryRound := make([]byte, 8)
+	binary.LittleEndian.PutUint64(binaryRound, round)
+	return crypto.Keccak256Hash(seed, binaryRound)
+}
+
 // GenerateRandomPrivateKeys generate a set of private keys.
 func GenerateRandomPrivateKeys(nodeCount int) (prvKeys []crypto.PrivateKey) {
 	for i := 0; i < nodeCount; i++ {
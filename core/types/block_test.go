@@ -21,6 +21,7 @@ import (
 	"math/rand"
 	"reflect"
 	"sort"
+	"sync"
 	"testing"
 	"time"
 
@@ -183,6 +184,28 @@ func (s *BlockTestSuite) TestClone() {
 	}
 }
 
+func (s *BlockTestSuite) TestCloneConcurrently() {
+	b := s.createRandomBlock()
+	origin := b.Clone()
+	// Clones are mutated concurrently, which should be reported by race
+	// detector if any slice is shared.
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			copied := b.Clone()
+			copied.Payload[0] = byte(i)
+			copied.Witness.Data[0] = byte(i)
+			copied.Randomness[0] = byte(i)
+			copied.Signature.Signature[0] = byte(i)
+			copied.CRSSignature.Signature[0] = byte(i)
+		}(i)
+	}
+	wg.Wait()
+	s.Require().True(reflect.DeepEqual(origin, b))
+}
+
 func (s *BlockTestSuite) TestRLPEncodeDecode() {
 	block := s.createRandomBlock()
 	b, err := rlp.EncodeToBytes(block)
@@ -34,16 +34,22 @@ type Config struct {
 	// Time related.
 	RoundLength      uint64
 	MinBlockInterval time.Duration
+	MaxClockSkew     time.Duration
+
+	// Block related.
+	MaxBlockPayloadSize uint64
 }
 
 // Clone return a copied configuration.
 func (c *Config) Clone() *Config {
 	return &Config{
-		LambdaBA:         c.LambdaBA,
-		LambdaDKG:        c.LambdaDKG,
-		NotarySetSize:    c.NotarySetSize,
-		RoundLength:      c.RoundLength,
-		MinBlockInterval: c.MinBlockInterval,
+		LambdaBA:            c.LambdaBA,
+		LambdaDKG:           c.LambdaDKG,
+		NotarySetSize:       c.NotarySetSize,
+		RoundLength:         c.RoundLength,
+		MinBlockInterval:    c.MinBlockInterval,
+		MaxClockSkew:        c.MaxClockSkew,
+		MaxBlockPayloadSize: c.MaxBlockPayloadSize,
 	}
 }
 
@@ -64,12 +70,26 @@ func (c *Config) Bytes() []byte {
 	binaryMinBlockInterval := make([]byte, 8)
 	binary.LittleEndian.PutUint64(binaryMinBlockInterval,
 		uint64(c.MinBlockInterval.Nanoseconds()))
+	binaryMaxClockSkew := make([]byte, 8)
+	binary.LittleEndian.PutUint64(binaryMaxClockSkew,
+		uint64(c.MaxClockSkew.Nanoseconds()))
+	binaryMaxBlockPayloadSize := make([]byte, 8)
+	binary.LittleEndian.PutUint64(
+		binaryMaxBlockPayloadSize, c.MaxBlockPayloadSize)
 
-	enc := make([]byte, 0, 40)
+	enc := make([]byte, 0, 56)
 	enc = append(enc, binaryLambdaBA...)
 	enc = append(enc, binaryLambdaDKG...)
 	enc = append(enc, binaryNotarySetSize...)
 	enc = append(enc, binaryRoundLength...)
 	enc = append(enc, binaryMinBlockInterval...)
+	// Fields appended later are encoded only when they, or the ones after
+	// them, are set. The encoding of configs without them is unchanged.
+	if c.MaxClockSkew != 0 || c.MaxBlockPayloadSize != 0 {
+		enc = append(enc, binaryMaxClockSkew...)
+	}
+	if c.MaxBlockPayloadSize != 0 {
+		enc = append(enc, binaryMaxBlockPayloadSize...)
+	}
 	return enc
 }
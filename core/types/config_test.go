@@ -35,10 +35,35 @@ func (s *ConfigTestSuite) TestClone() {
 		NotarySetSize:    5,
 		RoundLength:      1000,
 		MinBlockInterval: 7 * time.Nanosecond,
+		MaxClockSkew:     3 * time.Second,
+
+		MaxBlockPayloadSize: 1024,
 	}
 	s.Require().Equal(c, c.Clone())
 }
 
+func (s *ConfigTestSuite) TestBytes() {
+	c := &Config{
+		LambdaBA:         1 * time.Millisecond,
+		LambdaDKG:        2 * time.Hour,
+		NotarySetSize:    5,
+		RoundLength:      1000,
+		MinBlockInterval: 7 * time.Nanosecond,
+	}
+	// Unset fields appended later don't change the encoding.
+	base := c.Bytes()
+	s.Require().Len(base, 36)
+	c.MaxBlockPayloadSize = 1024
+	withPayloadSize := c.Bytes()
+	s.Require().Len(withPayloadSize, 52)
+	s.Require().Equal(base, withPayloadSize[:36])
+	c.MaxClockSkew = 3 * time.Second
+	s.Require().Len(c.Bytes(), 52)
+	s.Require().NotEqual(withPayloadSize, c.Bytes())
+	c.MaxBlockPayloadSize = 0
+	s.Require().Len(c.Bytes(), 44)
+}
+
 func TestConfig(t *testing.T) {
 	suite.Run(t, new(ConfigTestSuite))
 }
@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 
 	"github.com/dexon-foundation/dexon/rlp"
 
@@ -33,8 +34,9 @@ import (
 
 // Errors for typesDKG package.
 var (
-	ErrNotReachThreshold = fmt.Errorf("threshold not reach")
-	ErrInvalidThreshold  = fmt.Errorf("invalid threshold")
+	ErrNotReachThreshold       = fmt.Errorf("threshold not reach")
+	ErrInvalidThreshold        = fmt.Errorf("invalid threshold")
+	ErrMalformedGroupPublicKey = fmt.Errorf("malformed group public key")
 )
 
 // NewID creates a DKGID from NodeID.
@@ -64,6 +66,18 @@ func (p *PrivateShare) Equal(other *PrivateShare) bool {
 			p.PrivateShare.Bytes(), other.PrivateShare.Bytes()) == 0
 }
 
+// EncryptedPrivateShare carries a PrivateShare encrypted to its receiver. The
+// signature is made on the plaintext PrivateShare, thus it could only be
+// verified after decryption.
+type EncryptedPrivateShare struct {
+	ProposerID     types.NodeID     `json:"proposer_id"`
+	ReceiverID     types.NodeID     `json:"receiver_id"`
+	Round          uint64           `json:"round"`
+	Reset          uint64           `json:"reset"`
+	EncryptedShare []byte           `json:"encrypted_share"`
+	Signature      crypto.Signature `json:"signature"`
+}
+
 // MasterPublicKey decrtibe a master public key in DKG protocol.
 type MasterPublicKey struct {
 	ProposerID      types.NodeID              `json:"proposer_id"`
@@ -336,6 +350,109 @@ type GroupPublicKey struct {
 	IDMap          map[types.NodeID]cryptoDKG.ID
 	GroupPublicKey *cryptoDKG.PublicKey
 	Threshold      int
+
+	disqualifyNodeIDs map[types.NodeID]string
+}
+
+// DisqualifiedNodes returns the disqualified nodes and the reasons why they
+// are disqualified.
+func (gpk *GroupPublicKey) DisqualifiedNodes() map[types.NodeID]string {
+	ret := make(map[types.NodeID]string, len(gpk.disqualifyNodeIDs))
+	for nID, reason := range gpk.disqualifyNodeIDs {
+		ret[nID] = reason
+	}
+	return ret
+}
+
+// IsQualified checks if a node is qualified in DKG protocol.
+func (gpk *GroupPublicKey) IsQualified(nID types.NodeID) bool {
+	_, exist := gpk.QualifyNodeIDs[nID]
+	return exist
+}
+
+// QualifiedNodes returns qualified nodes sorted by their IDs.
+func (gpk *GroupPublicKey) QualifiedNodes() []types.NodeID {
+	return sortNodeIDs(gpk.QualifyNodeIDs)
+}
+
+type rlpGroupPublicKey struct {
+	Round             uint64
+	Threshold         uint64
+	QualifyIDs        [][]byte
+	QualifyNodeIDs    []types.NodeID
+	GroupPublicKey    []byte
+	DisqualifyNodeIDs []types.NodeID
+	DisqualifyReasons []string
+}
+
+// EncodeRLP implements rlp.Encoder
+func (gpk *GroupPublicKey) EncodeRLP(w io.Writer) error {
+	owners := make(map[string]types.NodeID, len(gpk.IDMap))
+	for nID, id := range gpk.IDMap {
+		owners[string(id.GetLittleEndian())] = nID
+	}
+	enc := rlpGroupPublicKey{
+		Round:          gpk.Round,
+		Threshold:      uint64(gpk.Threshold),
+		QualifyIDs:     make([][]byte, 0, len(gpk.QualifyIDs)),
+		QualifyNodeIDs: make([]types.NodeID, 0, len(gpk.QualifyIDs)),
+		GroupPublicKey: gpk.GroupPublicKey.Serialize(),
+	}
+	for _, id := range gpk.QualifyIDs {
+		b := id.GetLittleEndian()
+		enc.QualifyIDs = append(enc.QualifyIDs, b)
+		enc.QualifyNodeIDs = append(enc.QualifyNodeIDs, owners[string(b)])
+	}
+	disqualified := make(types.NodeIDs, 0, len(gpk.disqualifyNodeIDs))
+	for nID := range gpk.disqualifyNodeIDs {
+		disqualified = append(disqualified, nID)
+	}
+	sort.Sort(disqualified)
+	for _, nID := range disqualified {
+		enc.DisqualifyNodeIDs = append(enc.DisqualifyNodeIDs, nID)
+		enc.DisqualifyReasons = append(
+			enc.DisqualifyReasons, gpk.disqualifyNodeIDs[nID])
+	}
+	return rlp.Encode(w, enc)
+}
+
+// DecodeRLP implements rlp.Decoder
+func (gpk *GroupPublicKey) DecodeRLP(s *rlp.Stream) error {
+	var dec rlpGroupPublicKey
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	if len(dec.QualifyIDs) != len(dec.QualifyNodeIDs) ||
+		len(dec.DisqualifyNodeIDs) != len(dec.DisqualifyReasons) {
+		return ErrMalformedGroupPublicKey
+	}
+	pk := &cryptoDKG.PublicKey{}
+	if err := pk.Deserialize(dec.GroupPublicKey); err != nil {
+		return err
+	}
+	*gpk = GroupPublicKey{
+		Round:             dec.Round,
+		QualifyIDs:        make(cryptoDKG.IDs, 0, len(dec.QualifyIDs)),
+		QualifyNodeIDs:    make(map[types.NodeID]struct{}),
+		IDMap:             make(map[types.NodeID]cryptoDKG.ID),
+		GroupPublicKey:    pk,
+		Threshold:         int(dec.Threshold),
+		disqualifyNodeIDs: make(map[types.NodeID]string),
+	}
+	for i, b := range dec.QualifyIDs {
+		id, err := cryptoDKG.BytesID(b)
+		if err != nil {
+			return err
+		}
+		nID := dec.QualifyNodeIDs[i]
+		gpk.QualifyIDs = append(gpk.QualifyIDs, id)
+		gpk.QualifyNodeIDs[nID] = struct{}{}
+		gpk.IDMap[nID] = id
+	}
+	for i, nID := range dec.DisqualifyNodeIDs {
+		gpk.disqualifyNodeIDs[nID] = dec.DisqualifyReasons[i]
+	}
+	return nil
 }
 
 // VerifySignature verifies if the signature is correct.
@@ -344,17 +461,17 @@ func (gpk *GroupPublicKey) VerifySignature(
 	return gpk.GroupPublicKey.VerifySignature(hash, sig)
 }
 
-// CalcQualifyNodes returns the qualified nodes.
-func CalcQualifyNodes(
-	mpks []*MasterPublicKey, complaints []*Complaint, threshold int) (
-	qualifyIDs cryptoDKG.IDs, qualifyNodeIDs map[types.NodeID]struct{}, err error) {
-	if len(mpks) < threshold {
-		err = ErrInvalidThreshold
-		return
-	}
+// Reasons why a node is disqualified in DKG protocol.
+const (
+	DisqualifyReasonNack      = "nack complaints reach threshold"
+	DisqualifyReasonComplaint = "justified complaint"
+)
 
-	// Calculate qualify members.
-	disqualifyIDs := map[types.NodeID]struct{}{}
+// CalcDisqualifyNodes returns the disqualified nodes and the reasons why they
+// are disqualified.
+func CalcDisqualifyNodes(
+	complaints []*Complaint, threshold int) map[types.NodeID]string {
+	disqualifyIDs := map[types.NodeID]string{}
 	complaintsByID := map[types.NodeID]map[types.NodeID]struct{}{}
 	for _, complaint := range complaints {
 		if complaint.IsNack() {
@@ -365,27 +482,50 @@ func CalcQualifyNodes(
 			complaintsByID[complaint.PrivateShare.ProposerID][complaint.ProposerID] =
 				struct{}{}
 		} else {
-			disqualifyIDs[complaint.PrivateShare.ProposerID] = struct{}{}
+			disqualifyIDs[complaint.PrivateShare.ProposerID] =
+				DisqualifyReasonComplaint
 		}
 	}
 	for nID, complaints := range complaintsByID {
+		if _, exist := disqualifyIDs[nID]; exist {
+			continue
+		}
 		if len(complaints) >= threshold {
-			disqualifyIDs[nID] = struct{}{}
+			disqualifyIDs[nID] = DisqualifyReasonNack
 		}
 	}
-	qualifyIDs = make(cryptoDKG.IDs, 0, len(mpks)-len(disqualifyIDs))
-	if cap(qualifyIDs) < threshold {
-		err = ErrNotReachThreshold
+	return disqualifyIDs
+}
+
+// CalcQualifyNodes returns the qualified nodes.
+func CalcQualifyNodes(
+	mpks []*MasterPublicKey, complaints []*Complaint, threshold int) (
+	qualifyIDs cryptoDKG.IDs, qualifyNodeIDs map[types.NodeID]struct{}, err error) {
+	if len(mpks) < threshold {
+		err = ErrInvalidThreshold
 		return
 	}
+
+	// Calculate qualify members. Nodes disqualified might not propose master
+	// public keys, thus the qualify set could only be counted after filtering.
+	disqualifyIDs := CalcDisqualifyNodes(complaints, threshold)
+	qualifyIDs = make(cryptoDKG.IDs, 0, len(mpks))
 	qualifyNodeIDs = make(map[types.NodeID]struct{})
 	for _, mpk := range mpks {
 		if _, exist := disqualifyIDs[mpk.ProposerID]; exist {
 			continue
 		}
+		if _, exist := qualifyNodeIDs[mpk.ProposerID]; exist {
+			continue
+		}
 		qualifyIDs = append(qualifyIDs, mpk.DKGID)
 		qualifyNodeIDs[mpk.ProposerID] = struct{}{}
 	}
+	if len(qualifyIDs) < threshold {
+		qualifyIDs, qualifyNodeIDs = nil, nil
+		err = ErrNotReachThreshold
+		return
+	}
 	return
 }
 
@@ -416,12 +556,13 @@ func NewGroupPublicKey(
 	}
 	groupPK := cryptoDKG.RecoverGroupPublicKey(pubShares)
 	return &GroupPublicKey{
-		Round:          round,
-		QualifyIDs:     qualifyIDs,
-		QualifyNodeIDs: qualifyNodeIDs,
-		IDMap:          idMap,
-		Threshold:      threshold,
-		GroupPublicKey: groupPK,
+		Round:             round,
+		QualifyIDs:        qualifyIDs,
+		QualifyNodeIDs:    qualifyNodeIDs,
+		IDMap:             idMap,
+		Threshold:         threshold,
+		GroupPublicKey:    groupPK,
+		disqualifyNodeIDs: CalcDisqualifyNodes(complaints, threshold),
 	}, nil
 }
 
@@ -435,6 +576,26 @@ type NodePublicKeys struct {
 	Threshold      int
 }
 
+// IsQualified checks if a node is qualified in DKG protocol.
+func (npks *NodePublicKeys) IsQualified(nID types.NodeID) bool {
+	_, exist := npks.QualifyNodeIDs[nID]
+	return exist
+}
+
+// QualifiedNodes returns qualified nodes sorted by their IDs.
+func (npks *NodePublicKeys) QualifiedNodes() []types.NodeID {
+	return sortNodeIDs(npks.QualifyNodeIDs)
+}
+
+func sortNodeIDs(nIDs map[types.NodeID]struct{}) []types.NodeID {
+	ret := make(types.NodeIDs, 0, len(nIDs))
+	for nID := range nIDs {
+		ret = append(ret, nID)
+	}
+	sort.Sort(ret)
+	return ret
+}
+
 // NewNodePublicKeys creats a NodePublicKeys instance.
 func NewNodePublicKeys(
 	round uint64,
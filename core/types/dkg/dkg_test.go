@@ -20,6 +20,7 @@ package dkg
 import (
 	"math/rand"
 	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -430,6 +431,167 @@ func (s *DKGTestSuite) TestSuccessEquality() {
 	req.True(success1.Equal(success2))
 }
 
+func (s *DKGTestSuite) TestCalcDisqualifyNodes() {
+	var req = s.Require()
+	threshold := 2
+	nIDs := make([]types.NodeID, 4)
+	mpks := make([]*MasterPublicKey, 0, len(nIDs))
+	for i := range nIDs {
+		nIDs[i] = types.NodeID{Hash: common.NewRandomHash()}
+		mpks = append(mpks, &MasterPublicKey{
+			ProposerID: nIDs[i],
+			DKGID:      NewID(nIDs[i]),
+		})
+	}
+	nack := func(from, to types.NodeID) *Complaint {
+		return &Complaint{
+			ProposerID:   from,
+			PrivateShare: PrivateShare{ProposerID: to},
+		}
+	}
+	complaints := []*Complaint{
+		// nIDs[0] is nacked by enough nodes.
+		nack(nIDs[1], nIDs[0]),
+		nack(nIDs[2], nIDs[0]),
+		// nIDs[1] is nacked, but not enough.
+		nack(nIDs[0], nIDs[1]),
+		// nIDs[2] sent an incorrect private share.
+		{
+			ProposerID: nIDs[3],
+			PrivateShare: PrivateShare{
+				ProposerID: nIDs[2],
+				ReceiverID: nIDs[3],
+				Signature: crypto.Signature{
+					Signature: s.genRandomBytes(),
+				},
+			},
+		},
+	}
+	disqualified := CalcDisqualifyNodes(complaints, threshold)
+	req.Equal(map[types.NodeID]string{
+		nIDs[0]: DisqualifyReasonNack,
+		nIDs[2]: DisqualifyReasonComplaint,
+	}, disqualified)
+	_, qualified, err := CalcQualifyNodes(mpks, complaints, threshold)
+	req.NoError(err)
+	req.Len(qualified, 2)
+	req.Contains(qualified, nIDs[1])
+	req.Contains(qualified, nIDs[3])
+	// Check qualify set membership.
+	gpk := &GroupPublicKey{QualifyNodeIDs: qualified}
+	npks := &NodePublicKeys{QualifyNodeIDs: qualified}
+	expected := types.NodeIDs{nIDs[1], nIDs[3]}
+	sort.Sort(expected)
+	req.Equal([]types.NodeID(expected), gpk.QualifiedNodes())
+	req.Equal([]types.NodeID(expected), npks.QualifiedNodes())
+	for i, nID := range nIDs {
+		req.Equal(i == 1 || i == 3, gpk.IsQualified(nID))
+		req.Equal(i == 1 || i == 3, npks.IsQualified(nID))
+	}
+}
+
+func (s *DKGTestSuite) TestNotReachThreshold() {
+	var req = s.Require()
+	threshold := 3
+	nIDs := make([]types.NodeID, 4)
+	mpks := make([]*MasterPublicKey, 0, len(nIDs))
+	for i := range nIDs {
+		nIDs[i] = types.NodeID{Hash: common.NewRandomHash()}
+		_, pubShare := cryptoDKG.NewPrivateKeyShares(threshold)
+		mpks = append(mpks, &MasterPublicKey{
+			ProposerID:      nIDs[i],
+			DKGID:           NewID(nIDs[i]),
+			PublicKeyShares: *pubShare.Move(),
+		})
+	}
+	disqualify := func(nID types.NodeID) *Complaint {
+		return &Complaint{
+			ProposerID: nIDs[0],
+			PrivateShare: PrivateShare{
+				ProposerID: nID,
+				Signature: crypto.Signature{
+					Signature: s.genRandomBytes(),
+				},
+			},
+		}
+	}
+	// Only 2 nodes are qualified after disqualifying 2 nodes.
+	complaints := []*Complaint{disqualify(nIDs[1]), disqualify(nIDs[2])}
+	_, _, err := CalcQualifyNodes(mpks, complaints, threshold)
+	req.Equal(ErrNotReachThreshold, err)
+	_, err = NewGroupPublicKey(10, mpks, complaints, threshold)
+	req.Equal(ErrNotReachThreshold, err)
+	_, err = NewNodePublicKeys(10, mpks, complaints, threshold)
+	req.Equal(ErrNotReachThreshold, err)
+	// Disqualified nodes without master public keys don't count.
+	complaints = []*Complaint{
+		disqualify(nIDs[1]),
+		disqualify(types.NodeID{Hash: common.NewRandomHash()}),
+		disqualify(types.NodeID{Hash: common.NewRandomHash()}),
+		disqualify(types.NodeID{Hash: common.NewRandomHash()}),
+		disqualify(types.NodeID{Hash: common.NewRandomHash()}),
+	}
+	gpk, err := NewGroupPublicKey(10, mpks, complaints, threshold)
+	req.NoError(err)
+	req.Len(gpk.QualifyIDs, 3)
+	req.False(gpk.IsQualified(nIDs[1]))
+	// Duplicated master public keys don't count.
+	_, _, err = CalcQualifyNodes(
+		[]*MasterPublicKey{mpks[0], mpks[0], mpks[1]}, nil, threshold)
+	req.Equal(ErrNotReachThreshold, err)
+}
+
+func (s *DKGTestSuite) TestGroupPublicKeyRLPEncodeDecode() {
+	var req = s.Require()
+	threshold := 2
+	nIDs := make([]types.NodeID, 4)
+	mpks := make([]*MasterPublicKey, 0, len(nIDs))
+	for i := range nIDs {
+		nIDs[i] = types.NodeID{Hash: common.NewRandomHash()}
+		_, pubShare := cryptoDKG.NewPrivateKeyShares(threshold)
+		mpks = append(mpks, &MasterPublicKey{
+			ProposerID:      nIDs[i],
+			DKGID:           NewID(nIDs[i]),
+			PublicKeyShares: *pubShare.Move(),
+		})
+	}
+	complaints := []*Complaint{
+		{
+			ProposerID:   nIDs[1],
+			PrivateShare: PrivateShare{ProposerID: nIDs[0]},
+		},
+		{
+			ProposerID:   nIDs[2],
+			PrivateShare: PrivateShare{ProposerID: nIDs[0]},
+		},
+	}
+	gpk, err := NewGroupPublicKey(10, mpks, complaints, threshold)
+	req.NoError(err)
+	b, err := rlp.EncodeToBytes(gpk)
+	req.NoError(err)
+	gpk2 := &GroupPublicKey{}
+	req.NoError(rlp.DecodeBytes(b, gpk2))
+	req.Equal(gpk.Round, gpk2.Round)
+	req.Equal(gpk.Threshold, gpk2.Threshold)
+	req.Equal(gpk.QualifyNodeIDs, gpk2.QualifyNodeIDs)
+	req.Equal(gpk.DisqualifiedNodes(), gpk2.DisqualifiedNodes())
+	req.Equal(gpk.GroupPublicKey.Bytes(), gpk2.GroupPublicKey.Bytes())
+	req.Len(gpk2.QualifyIDs, len(gpk.QualifyIDs))
+	for i := range gpk.QualifyIDs {
+		req.Equal(gpk.QualifyIDs[i].GetHexString(),
+			gpk2.QualifyIDs[i].GetHexString())
+	}
+	req.Len(gpk2.IDMap, len(gpk.IDMap))
+	for nID, id := range gpk.IDMap {
+		id2, exist := gpk2.IDMap[nID]
+		req.True(exist)
+		req.Equal(id.GetHexString(), id2.GetHexString())
+	}
+	bb, err := rlp.EncodeToBytes(gpk2)
+	req.NoError(err)
+	req.Equal(b, bb)
+}
+
 func TestDKG(t *testing.T) {
 	suite.Run(t, new(DKGTestSuite))
 }
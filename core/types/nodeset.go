@@ -21,6 +21,7 @@ import (
 	"container/heap"
 	"encoding/binary"
 	"math/big"
+	"sort"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto"
@@ -48,11 +49,20 @@ type nodeRank struct {
 	rank *big.Int
 }
 
+// less compares ranks, ties are broken by node ID to make sure all nodes get
+// the same sub set.
+func (r *nodeRank) less(other *nodeRank) bool {
+	if cmp := r.rank.Cmp(other.rank); cmp != 0 {
+		return cmp < 0
+	}
+	return r.ID.Hash.Less(other.ID.Hash)
+}
+
 // rankHeap is a MaxHeap structure.
 type rankHeap []*nodeRank
 
 func (h rankHeap) Len() int           { return len(h) }
-func (h rankHeap) Less(i, j int) bool { return h[i].rank.Cmp(h[j].rank) > 0 }
+func (h rankHeap) Less(i, j int) bool { return h[j].less(h[i]) }
 func (h rankHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
 func (h *rankHeap) Push(x interface{}) {
 	*h = append(*h, x.(*nodeRank))
@@ -109,6 +119,24 @@ func (ns *NodeSet) Clone() *NodeSet {
 	return nsCopy
 }
 
+// Diff returns nodes in other but not in ns as added, and nodes in ns but not
+// in other as removed. Both slices are sorted by NodeID.
+func (ns *NodeSet) Diff(other *NodeSet) (added, removed []NodeID) {
+	for nID := range other.IDs {
+		if _, exists := ns.IDs[nID]; !exists {
+			added = append(added, nID)
+		}
+	}
+	for nID := range ns.IDs {
+		if _, exists := other.IDs[nID]; !exists {
+			removed = append(removed, nID)
+		}
+	}
+	sort.Sort(NodeIDs(added))
+	sort.Sort(NodeIDs(removed))
+	return
+}
+
 // GetSubSet returns the subset of given target.
 func (ns *NodeSet) GetSubSet(
 	size int, target *SubSetTarget) map[NodeID]struct{} {
@@ -125,7 +153,7 @@ func (ns *NodeSet) GetSubSet(
 		}
 		if idx >= size {
 			rank := newNodeRank(nID, target)
-			if rank.rank.Cmp(h[0].rank) < 0 {
+			if rank.less(h[0]) {
 				h[0] = rank
 				heap.Fix(&h, 0)
 			}
@@ -18,6 +18,7 @@
 package types
 
 import (
+	"math/big"
 	"testing"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
@@ -54,6 +55,33 @@ func (s *NodeSetTestSuite) TestGetSubSet() {
 	}
 }
 
+func (s *NodeSetTestSuite) TestGetSubSetDeterministic() {
+	total := 20
+	crs := common.NewRandomHash()
+	nodes1 := NewNodeSet()
+	for len(nodes1.IDs) < total {
+		nodes1.Add(NodeID{common.NewRandomHash()})
+	}
+	target := NewNotarySetTarget(crs)
+	for i := 0; i < 10; i++ {
+		// Rebuild the node set to have a different map layout.
+		nodes2 := NewNodeSet()
+		for nID := range nodes1.IDs {
+			nodes2.Add(nID)
+		}
+		s.Equal(nodes1.GetSubSet(7, target),
+			nodes2.GetSubSet(7, NewNotarySetTarget(crs)))
+	}
+	// Ties in rank are broken by node ID.
+	r1 := &nodeRank{ID: NodeID{common.Hash{1}}, rank: big.NewInt(1)}
+	r2 := &nodeRank{ID: NodeID{common.Hash{2}}, rank: big.NewInt(1)}
+	r3 := &nodeRank{ID: NodeID{common.Hash{0}}, rank: big.NewInt(2)}
+	s.True(r1.less(r2))
+	s.False(r2.less(r1))
+	s.False(r1.less(r1))
+	s.True(r2.less(r3))
+}
+
 func (s *NodeSetTestSuite) TestGetSubSetZeroSize() {
 	total := 10
 	nodes := NewNodeSet()
@@ -65,6 +93,37 @@ func (s *NodeSetTestSuite) TestGetSubSetZeroSize() {
 	s.Len(emptySet, 0)
 }
 
+func (s *NodeSetTestSuite) TestDiff() {
+	var (
+		kept     = NodeID{common.NewRandomHash()}
+		left     = NodeID{common.NewRandomHash()}
+		joined   = NodeID{common.NewRandomHash()}
+		oldSet   = NewNodeSet()
+		newSet   = NewNodeSet()
+		emptySet = NewNodeSet()
+	)
+	oldSet.Add(kept)
+	oldSet.Add(left)
+	newSet.Add(kept)
+	newSet.Add(joined)
+	added, removed := oldSet.Diff(newSet)
+	s.Equal([]NodeID{joined}, added)
+	s.Equal([]NodeID{left}, removed)
+	// Swapping both sets swaps the result.
+	added, removed = newSet.Diff(oldSet)
+	s.Equal([]NodeID{left}, added)
+	s.Equal([]NodeID{joined}, removed)
+	// Identical sets.
+	added, removed = oldSet.Diff(oldSet.Clone())
+	s.Empty(added)
+	s.Empty(removed)
+	// Diff with an empty set.
+	added, removed = emptySet.Diff(oldSet)
+	s.Len(added, 2)
+	s.Empty(removed)
+	s.True(NodeIDs(added).Less(0, 1))
+}
+
 func TestNodeSet(t *testing.T) {
 	suite.Run(t, new(NodeSetTestSuite))
 }
@@ -20,6 +20,7 @@ package utils
 import (
 	"bytes"
 	"encoding/binary"
+	"sync"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto"
@@ -90,6 +91,35 @@ func VerifyBlockSignatureWithoutPayload(b *types.Block) (err error) {
 
 }
 
+// VerifyBlocks verifies signatures of blocks by a pool of workers, errors are
+// returned in the same order of blocks, nil for the verified ones.
+func VerifyBlocks(blocks []*types.Block, workers int) []error {
+	errs := make([]error, len(blocks))
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(blocks) {
+		workers = len(blocks)
+	}
+	idxCh := make(chan int, len(blocks))
+	for idx := range blocks {
+		idxCh <- idx
+	}
+	close(idxCh)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for idx := range idxCh {
+				errs[idx] = VerifyBlockSignature(blocks[idx])
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
 // HashVote generates hash of a types.Vote.
 func HashVote(vote *types.Vote) common.Hash {
 	binaryPeriod := make([]byte, 8)
@@ -108,6 +108,25 @@ func (s *CryptoTestSuite) TestBlockSignature() {
 	}
 }
 
+func (s *CryptoTestSuite) TestVerifyBlocks() {
+	prv, err := ecdsa.NewPrivateKey()
+	s.Require().NoError(err)
+	blocks := s.generateBlockChain(10, NewSigner(prv))
+	blocks[3].Payload = []byte("tampered")
+	blocks[7].Hash = common.NewRandomHash()
+	for _, workers := range []int{0, 1, 4, 20} {
+		errs := VerifyBlocks(blocks, workers)
+		s.Require().Len(errs, len(blocks))
+		for idx, err := range errs {
+			s.Equal(VerifyBlockSignature(blocks[idx]), err)
+		}
+		s.Equal(ErrIncorrectHash, errs[3])
+		s.Equal(ErrIncorrectHash, errs[7])
+		s.NoError(errs[0])
+	}
+	s.Empty(VerifyBlocks(nil, 4))
+}
+
 func (s *CryptoTestSuite) TestVoteSignature() {
 	prv, err := ecdsa.NewPrivateKey()
 	s.Require().NoError(err)
@@ -20,6 +20,7 @@ package utils
 import (
 	"errors"
 	"sync"
+	"sync/atomic"
 
 	"github.com/dexon-foundation/dexon-consensus/common"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto"
@@ -35,6 +36,21 @@ var (
 	ErrConfigurationNotReady = errors.New("configuration is not ready")
 )
 
+// keyPoolShardCount is the count of shards of the public key pool.
+const keyPoolShardCount = 16
+
+type keyRecord struct {
+	pubKey crypto.PublicKey
+	refCnt int
+}
+
+// keyPoolShard is a shard of the public key pool, and is protected by its own
+// lock.
+type keyPoolShard struct {
+	lock sync.RWMutex
+	keys map[types.NodeID]*keyRecord
+}
+
 type sets struct {
 	crs       common.Hash
 	nodeSet   *types.NodeSet
@@ -61,25 +77,38 @@ type NodeSetCacheInterface interface {
 // NOTE: this module doesn't handle DKG resetting and can only be used along
 //       with utils.RoundEvent.
 type NodeSetCache struct {
+	// Counters are placed first to keep them 64-bit aligned for atomic
+	// operations.
+	hits    uint64
+	misses  uint64
+	updates uint64
+	purges  uint64
 	lock    sync.RWMutex
 	nsIntf  NodeSetCacheInterface
 	rounds  map[uint64]*sets
-	keyPool map[types.NodeID]*struct {
-		pubKey crypto.PublicKey
-		refCnt int
-	}
+	keyPool [keyPoolShardCount]keyPoolShard
+}
+
+// NodeSetCacheStats is the statistics of NodeSetCache.
+type NodeSetCacheStats struct {
+	Hits    uint64
+	Misses  uint64
+	Updates uint64
+	Purges  uint64
+	// Rounds is the count of rounds currently cached.
+	Rounds int
 }
 
 // NewNodeSetCache constructs an NodeSetCache instance.
 func NewNodeSetCache(nsIntf NodeSetCacheInterface) *NodeSetCache {
-	return &NodeSetCache{
+	cache := &NodeSetCache{
 		nsIntf: nsIntf,
 		rounds: make(map[uint64]*sets),
-		keyPool: make(map[types.NodeID]*struct {
-			pubKey crypto.PublicKey
-			refCnt int
-		}),
 	}
+	for i := range cache.keyPool {
+		cache.keyPool[i].keys = make(map[types.NodeID]*keyRecord)
+	}
+	return cache
 }
 
 // Exists checks if a node is in node set of that round.
@@ -100,10 +129,11 @@ func (cache *NodeSetCache) Exists(
 func (cache *NodeSetCache) GetPublicKey(
 	nodeID types.NodeID) (key crypto.PublicKey, exists bool) {
 
-	cache.lock.RLock()
-	defer cache.lock.RUnlock()
+	shard := cache.keyPoolShard(nodeID)
+	shard.lock.RLock()
+	defer shard.lock.RUnlock()
 
-	rec, exists := cache.keyPool[nodeID]
+	rec, exists := shard.keys[nodeID]
 	if exists {
 		key = rec.pubKey
 	}
@@ -132,6 +162,34 @@ func (cache *NodeSetCache) GetNotarySet(
 	return cache.cloneMap(IDs.notarySet), nil
 }
 
+// IsNotary checks if a node is in notary set of that round, without cloning
+// the whole set like GetNotarySet.
+func (cache *NodeSetCache) IsNotary(
+	round uint64, nodeID types.NodeID) (bool, error) {
+	IDs, err := cache.getOrUpdate(round)
+	if err != nil {
+		return false, err
+	}
+	_, exists := IDs.notarySet[nodeID]
+	return exists, nil
+}
+
+// RoundDiff returns nodes joined and left between node sets of round 'from'
+// and round 'to'.
+func (cache *NodeSetCache) RoundDiff(from, to uint64) (
+	added, removed []types.NodeID, err error) {
+	fromSet, err := cache.getOrUpdate(from)
+	if err != nil {
+		return
+	}
+	toSet, err := cache.getOrUpdate(to)
+	if err != nil {
+		return
+	}
+	added, removed = fromSet.nodeSet.Diff(toSet.nodeSet)
+	return
+}
+
 // Purge a specific round.
 func (cache *NodeSetCache) Purge(rID uint64) {
 	cache.lock.Lock()
@@ -140,13 +198,24 @@ func (cache *NodeSetCache) Purge(rID uint64) {
 	if !exist {
 		return
 	}
-	for nID := range nIDs.nodeSet.IDs {
-		rec := cache.keyPool[nID]
-		if rec.refCnt--; rec.refCnt == 0 {
-			delete(cache.keyPool, nID)
-		}
-	}
+	cache.releaseKeys(nIDs.nodeSet)
 	delete(cache.rounds, rID)
+	atomic.AddUint64(&cache.purges, 1)
+}
+
+// Stats returns the statistics of this cache, it could be used to check if
+// the count of cached rounds is suitable.
+func (cache *NodeSetCache) Stats() NodeSetCacheStats {
+	stats := NodeSetCacheStats{
+		Hits:    atomic.LoadUint64(&cache.hits),
+		Misses:  atomic.LoadUint64(&cache.misses),
+		Updates: atomic.LoadUint64(&cache.updates),
+		Purges:  atomic.LoadUint64(&cache.purges),
+	}
+	cache.lock.RLock()
+	defer cache.lock.RUnlock()
+	stats.Rounds = len(cache.rounds)
+	return stats
 }
 
 // Touch updates the internal cache of round.
@@ -164,6 +233,38 @@ func (cache *NodeSetCache) cloneMap(
 	return nIDsCopy
 }
 
+// keyPoolShard returns the key pool shard for that node.
+func (cache *NodeSetCache) keyPoolShard(nID types.NodeID) *keyPoolShard {
+	return &cache.keyPool[int(nID.Hash[0])%keyPoolShardCount]
+}
+
+// acquireKey adds a reference to the public key of that node.
+func (cache *NodeSetCache) acquireKey(nID types.NodeID, key crypto.PublicKey) {
+	shard := cache.keyPoolShard(nID)
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+	if rec, exists := shard.keys[nID]; exists {
+		rec.refCnt++
+	} else {
+		shard.keys[nID] = &keyRecord{key, 1}
+	}
+}
+
+// releaseKeys removes a reference to public keys of nodes in that node set.
+func (cache *NodeSetCache) releaseKeys(nodeSet *types.NodeSet) {
+	for nID := range nodeSet.IDs {
+		func() {
+			shard := cache.keyPoolShard(nID)
+			shard.lock.Lock()
+			defer shard.lock.Unlock()
+			rec := shard.keys[nID]
+			if rec.refCnt--; rec.refCnt == 0 {
+				delete(shard.keys, nID)
+			}
+		}()
+	}
+}
+
 func (cache *NodeSetCache) getOrUpdate(round uint64) (nIDs *sets, err error) {
 	s, exists := cache.get(round)
 	if !exists {
@@ -182,6 +283,7 @@ func (cache *NodeSetCache) getOrUpdate(round uint64) (nIDs *sets, err error) {
 func (cache *NodeSetCache) update(round uint64) (nIDs *sets, err error) {
 	cache.lock.Lock()
 	defer cache.lock.Unlock()
+	atomic.AddUint64(&cache.updates, 1)
 	// Get information for the requested round.
 	keySet := cache.nsIntf.NodeSet(round)
 	if keySet == nil {
@@ -198,14 +300,7 @@ func (cache *NodeSetCache) update(round uint64) (nIDs *sets, err error) {
 	for _, key := range keySet {
 		nID := types.NewNodeID(key)
 		nodeSet.Add(nID)
-		if rec, exists := cache.keyPool[nID]; exists {
-			rec.refCnt++
-		} else {
-			cache.keyPool[nID] = &struct {
-				pubKey crypto.PublicKey
-				refCnt int
-			}{key, 1}
-		}
+		cache.acquireKey(nID, key)
 	}
 	cfg := cache.nsIntf.Configuration(round)
 	if cfg == nil {
@@ -223,16 +318,12 @@ func (cache *NodeSetCache) update(round uint64) (nIDs *sets, err error) {
 	// Purge older rounds.
 	for rID, nIDs := range cache.rounds {
 		nodeSet := nIDs.nodeSet
-		if round-rID <= 5 {
+		if rID >= round || round-rID <= 5 {
 			continue
 		}
-		for nID := range nodeSet.IDs {
-			rec := cache.keyPool[nID]
-			if rec.refCnt--; rec.refCnt == 0 {
-				delete(cache.keyPool, nID)
-			}
-		}
+		cache.releaseKeys(nodeSet)
 		delete(cache.rounds, rID)
+		atomic.AddUint64(&cache.purges, 1)
 	}
 	return
 }
@@ -241,5 +332,10 @@ func (cache *NodeSetCache) get(round uint64) (nIDs *sets, exists bool) {
 	cache.lock.RLock()
 	defer cache.lock.RUnlock()
 	nIDs, exists = cache.rounds[round]
+	if exists {
+		atomic.AddUint64(&cache.hits, 1)
+	} else {
+		atomic.AddUint64(&cache.misses, 1)
+	}
 	return
 }
@@ -18,6 +18,7 @@
 package utils
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -29,9 +30,11 @@ import (
 )
 
 type nsIntf struct {
-	s       *NodeSetCacheTestSuite
-	crs     common.Hash
-	curKeys []crypto.PublicKey
+	s         *NodeSetCacheTestSuite
+	crs       common.Hash
+	fixedKeys []crypto.PublicKey
+	lock      sync.Mutex
+	roundKeys map[uint64][]crypto.PublicKey
 }
 
 func (g *nsIntf) Configuration(round uint64) (cfg *types.Config) {
@@ -44,14 +47,27 @@ func (g *nsIntf) Configuration(round uint64) (cfg *types.Config) {
 }
 func (g *nsIntf) CRS(round uint64) (b common.Hash) { return g.crs }
 func (g *nsIntf) NodeSet(round uint64) []crypto.PublicKey {
-	// Randomly generating keys, and check them for verification.
-	g.curKeys = []crypto.PublicKey{}
+	if g.fixedKeys != nil {
+		return g.fixedKeys
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if keys, exists := g.roundKeys[round]; exists {
+		return keys
+	}
+	// Randomly generating keys for each round, and check them for
+	// verification. Keys of a round are kept to be deterministic.
+	keys := []crypto.PublicKey{}
 	for i := 0; i < 10; i++ {
 		prvKey, err := ecdsa.NewPrivateKey()
 		g.s.Require().NoError(err)
-		g.curKeys = append(g.curKeys, prvKey.PublicKey())
+		keys = append(keys, prvKey.PublicKey())
+	}
+	if g.roundKeys == nil {
+		g.roundKeys = make(map[uint64][]crypto.PublicKey)
 	}
-	return g.curKeys
+	g.roundKeys[round] = keys
+	return keys
 }
 
 type NodeSetCacheTestSuite struct {
@@ -106,6 +122,28 @@ func (s *NodeSetCacheTestSuite) TestBasicUsage() {
 	}
 }
 
+func (s *NodeSetCacheTestSuite) TestIsNotary() {
+	var (
+		nsIntf = &nsIntf{
+			s:   s,
+			crs: common.NewRandomHash(),
+		}
+		cache = NewNodeSetCache(nsIntf)
+		req   = s.Require()
+	)
+	nodeSet, err := cache.GetNodeSet(0)
+	req.NoError(err)
+	notarySet, err := cache.GetNotarySet(0)
+	req.NoError(err)
+	req.Len(notarySet, 7)
+	for nID := range nodeSet.IDs {
+		isNotary, err := cache.IsNotary(0, nID)
+		req.NoError(err)
+		_, exists := notarySet[nID]
+		req.Equal(exists, isNotary)
+	}
+}
+
 func (s *NodeSetCacheTestSuite) TestTouch() {
 	var (
 		nsIntf = &nsIntf{
@@ -144,6 +182,182 @@ func (s *NodeSetCacheTestSuite) TestPurge() {
 	req.False(exist)
 }
 
+func (s *NodeSetCacheTestSuite) TestRefetchPurgedRound() {
+	var (
+		nsIntf = &nsIntf{
+			s:   s,
+			crs: common.NewRandomHash(),
+		}
+		cache = NewNodeSetCache(nsIntf)
+		req   = s.Require()
+	)
+	nodeSet, err := cache.GetNodeSet(1)
+	req.NoError(err)
+	req.NoError(cache.Touch(10))
+	_, exist := cache.get(1)
+	req.False(exist)
+	// A purged round is fetched from the interface again, and newer rounds
+	// should not be purged by it.
+	refetched, err := cache.GetNodeSet(1)
+	req.NoError(err)
+	req.Len(refetched.IDs, 10)
+	req.Equal(nodeSet.IDs, refetched.IDs)
+	_, exist = cache.get(10)
+	req.True(exist)
+}
+
+func (s *NodeSetCacheTestSuite) TestRoundDiff() {
+	var (
+		nsIntf = &nsIntf{
+			s:   s,
+			crs: common.NewRandomHash(),
+		}
+		cache = NewNodeSetCache(nsIntf)
+		req   = s.Require()
+	)
+	nodeSet1, err := cache.GetNodeSet(1)
+	req.NoError(err)
+	nodeSet2, err := cache.GetNodeSet(2)
+	req.NoError(err)
+	// Keys are randomly generated for each round, so all nodes are replaced.
+	added, removed, err := cache.RoundDiff(1, 2)
+	req.NoError(err)
+	req.Len(added, len(nodeSet2.IDs))
+	req.Len(removed, len(nodeSet1.IDs))
+	for _, nID := range added {
+		_, exists := nodeSet2.IDs[nID]
+		req.True(exists)
+	}
+	for _, nID := range removed {
+		_, exists := nodeSet1.IDs[nID]
+		req.True(exists)
+	}
+	added, removed, err = cache.RoundDiff(1, 1)
+	req.NoError(err)
+	req.Empty(added)
+	req.Empty(removed)
+	// Not ready rounds should be reported.
+	nsIntf.crs = common.Hash{}
+	_, _, err = cache.RoundDiff(1, 3)
+	req.Equal(ErrCRSNotReady, err)
+}
+
+func (s *NodeSetCacheTestSuite) TestStats() {
+	var (
+		nsIntf = &nsIntf{
+			s:   s,
+			crs: common.NewRandomHash(),
+		}
+		cache = NewNodeSetCache(nsIntf)
+		req   = s.Require()
+	)
+	req.Equal(NodeSetCacheStats{}, cache.Stats())
+	// A miss triggers an update.
+	_, err := cache.GetNotarySet(0)
+	req.NoError(err)
+	req.Equal(NodeSetCacheStats{Misses: 1, Updates: 1, Rounds: 1},
+		cache.Stats())
+	// A hit.
+	_, err = cache.GetNotarySet(0)
+	req.NoError(err)
+	req.Equal(NodeSetCacheStats{
+		Hits: 1, Misses: 1, Updates: 1, Rounds: 1}, cache.Stats())
+	// Updating round 6 purges round 0.
+	req.NoError(cache.Touch(6))
+	req.Equal(NodeSetCacheStats{
+		Hits: 1, Misses: 1, Updates: 2, Purges: 1, Rounds: 1}, cache.Stats())
+	cache.Purge(6)
+	req.Equal(NodeSetCacheStats{
+		Hits: 1, Misses: 1, Updates: 2, Purges: 2}, cache.Stats())
+}
+
 func TestNodeSetCache(t *testing.T) {
 	suite.Run(t, new(NodeSetCacheTestSuite))
 }
+
+// keyCache is the subset of NodeSetCache used in benchmarks.
+type keyCache interface {
+	GetPublicKey(types.NodeID) (crypto.PublicKey, bool)
+	Touch(uint64) error
+}
+
+// singleLockNodeSetCache serializes the key pool access of NodeSetCache with
+// one lock, as it was before the key pool got sharded: readers hold the read
+// lock while updates hold the write lock for the whole round update.
+type singleLockNodeSetCache struct {
+	lock  sync.RWMutex
+	cache *NodeSetCache
+}
+
+func (c *singleLockNodeSetCache) GetPublicKey(
+	nID types.NodeID) (crypto.PublicKey, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.cache.GetPublicKey(nID)
+}
+
+func (c *singleLockNodeSetCache) Touch(round uint64) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.cache.Touch(round)
+}
+
+func benchmarkGetPublicKeyWithUpdates(
+	b *testing.B, wrap func(*NodeSetCache) keyCache) {
+	keys := make([]crypto.PublicKey, 0, 100)
+	for i := 0; i < cap(keys); i++ {
+		prvKey, err := ecdsa.NewPrivateKey()
+		if err != nil {
+			b.Fatal(err)
+		}
+		keys = append(keys, prvKey.PublicKey())
+	}
+	cache := wrap(NewNodeSetCache(&nsIntf{
+		crs:       common.NewRandomHash(),
+		fixedKeys: keys,
+	}))
+	if err := cache.Touch(0); err != nil {
+		b.Fatal(err)
+	}
+	nIDs := make([]types.NodeID, 0, len(keys))
+	for _, k := range keys {
+		nIDs = append(nIDs, types.NewNodeID(k))
+	}
+	// Keep updating rounds to contend with readers.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for round := uint64(1); ; round++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if err := cache.Touch(round); err != nil {
+				panic(err)
+			}
+		}
+	}()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		idx := 0
+		for pb.Next() {
+			if _, exists := cache.GetPublicKey(nIDs[idx%len(nIDs)]); !exists {
+				panic("key should exist")
+			}
+			idx++
+		}
+	})
+}
+
+func BenchmarkGetPublicKeyWithUpdates(b *testing.B) {
+	benchmarkGetPublicKeyWithUpdates(b, func(c *NodeSetCache) keyCache {
+		return c
+	})
+}
+
+func BenchmarkGetPublicKeyWithUpdatesSingleLock(b *testing.B) {
+	benchmarkGetPublicKeyWithUpdates(b, func(c *NodeSetCache) keyCache {
+		return &singleLockNodeSetCache{cache: c}
+	})
+}
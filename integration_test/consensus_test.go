@@ -305,6 +305,73 @@ Loop:
 	s.verifyNodes(nodes)
 }
 
+func (s *ConsensusTestSuite) TestPauseResume() {
+	// Pause one node and drop all messages it receives, let the others keep
+	// going, then resume it and make sure it catches up by pulling what it
+	// missed.
+	var (
+		req         = s.Require()
+		peerCount   = 4
+		dMoment     = time.Now().UTC()
+		pauseHeight = uint64(20)
+		gapHeight   = uint64(20)
+	)
+	prvKeys, pubKeys, err := test.NewKeys(peerCount)
+	req.NoError(err)
+	seedGov, err := test.NewGovernance(
+		test.NewState(core.DKGDelayRound,
+			pubKeys, 100*time.Millisecond, &common.NullLogger{}, true),
+		core.ConfigRoundShift)
+	req.NoError(err)
+	req.NoError(seedGov.State().RequestChange(
+		test.StateChangeRoundLength, uint64(100)))
+	nodes := s.setupNodes(dMoment, prvKeys, seedGov)
+	var pausedNode *node
+	for _, n := range nodes {
+		if pausedNode == nil {
+			pausedNode = n
+		}
+		go n.con.Run()
+		defer n.con.Stop()
+	}
+	waitHeight := func(ns []*node, height uint64) {
+		deadline := time.Now().Add(5 * time.Minute)
+	Loop:
+		for {
+			req.True(time.Now().Before(deadline),
+				"unable to reach height %d", height)
+			<-time.After(1 * time.Second)
+			for _, n := range ns {
+				if n.app.GetLatestDeliveredPosition().Height < height {
+					continue Loop
+				}
+			}
+			return
+		}
+	}
+	var otherNodes []*node
+	for _, n := range nodes {
+		if n != pausedNode {
+			otherNodes = append(otherNodes, n)
+		}
+	}
+	waitHeight([]*node{pausedNode}, pauseHeight)
+	pausedNode.con.Pause(true)
+	req.True(pausedNode.con.Paused())
+	stoppedAt := pausedNode.app.GetLatestDeliveredPosition().Height
+	// The remaining nodes are still more than 2/3 of the notary set.
+	waitHeight(otherNodes, stoppedAt+gapHeight)
+	req.True(pausedNode.app.GetLatestDeliveredPosition().Height <
+		stoppedAt+gapHeight)
+	pausedNode.con.Resume()
+	req.False(pausedNode.con.Paused())
+	waitHeight([]*node{pausedNode}, stoppedAt+gapHeight)
+	for _, n := range nodes {
+		n.con.Stop()
+	}
+	s.verifyNodes(nodes)
+}
+
 func (s *ConsensusTestSuite) TestSetSizeChange() {
 	var (
 		req        = s.Require()
@@ -36,6 +36,9 @@ type Consensus struct {
 	NotarySetSize    uint32
 	DKGSetSize       uint32 `toml:"dkg_set_size"`
 	MinBlockInterval int
+	// CRSSeed makes CRS of each round derived from this seed when not empty,
+	// which makes notary sets and leaders identical in every run.
+	CRSSeed string `toml:"crs_seed"`
 }
 
 // Legacy config.
@@ -57,6 +60,8 @@ type Node struct {
 type LatencyModel struct {
 	Mean  float64
 	Sigma float64
+	// Seed makes latencies reproducible when not zero.
+	Seed int64
 }
 
 // Networking config.
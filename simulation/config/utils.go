@@ -37,6 +37,10 @@ func StateChangeTypeFromString(s string) test.StateChangeType {
 		return test.StateChangeMinBlockInterval
 	case "notary_set_size":
 		return test.StateChangeNotarySetSize
+	case "max_clock_skew":
+		return test.StateChangeMaxClockSkew
+	case "max_block_payload_size":
+		return test.StateChangeMaxBlockPayloadSize
 	}
 	panic(fmt.Errorf("unsupported state change type %s", s))
 }
@@ -52,8 +56,15 @@ func StateChangeValueFromString(
 			panic(err)
 		}
 		return uint32(ret)
+	case test.StateChangeMaxBlockPayloadSize:
+		ret, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		return ret
 	case test.StateChangeLambdaBA, test.StateChangeLambdaDKG,
-		test.StateChangeRoundLength, test.StateChangeMinBlockInterval:
+		test.StateChangeRoundLength, test.StateChangeMinBlockInterval,
+		test.StateChangeMaxClockSkew:
 		ret, err := strconv.ParseInt(v, 10, 32)
 		if err != nil {
 			panic(err)
@@ -71,18 +71,12 @@ func newNode(prvKey crypto.PrivateKey, logger common.Logger,
 	cfg config.Config) *node {
 	pubKey := prvKey.PublicKey()
 	netModule := test.NewNetwork(pubKey, test.NetworkConfig{
-		Type:       cfg.Networking.Type,
-		PeerServer: cfg.Networking.PeerServer,
-		PeerPort:   peerPort,
-		DirectLatency: &test.NormalLatencyModel{
-			Mean:  cfg.Networking.Direct.Mean,
-			Sigma: cfg.Networking.Direct.Sigma,
-		},
-		GossipLatency: &test.NormalLatencyModel{
-			Mean:  cfg.Networking.Gossip.Mean,
-			Sigma: cfg.Networking.Gossip.Sigma,
-		},
-		Marshaller: test.NewDefaultMarshaller(&jsonMarshaller{})})
+		Type:          cfg.Networking.Type,
+		PeerServer:    cfg.Networking.PeerServer,
+		PeerPort:      peerPort,
+		DirectLatency: newLatencyModel(cfg.Networking.Direct),
+		GossipLatency: newLatencyModel(cfg.Networking.Gossip),
+		Marshaller:    test.NewDefaultMarshaller(&jsonMarshaller{})})
 	id := types.NewNodeID(pubKey)
 	dbInst, err := db.NewMemBackedDB(id.String() + ".db")
 	if err != nil {
@@ -214,6 +208,9 @@ func (n *node) prepareConfigs() {
 	n.gov.State().RequestChange(test.StateChangeMinBlockInterval, time.Duration(
 		cConfig.MinBlockInterval)*time.Millisecond) // #nosec G104
 	n.gov.State().ProposeCRS(0, crypto.Keccak256Hash([]byte(cConfig.GenesisCRS))) // #nosec G104
+	if cConfig.CRSSeed != "" {
+		n.gov.UseDeterministicCRS([]byte(cConfig.CRSSeed))
+	}
 	// These rounds are not safe to be registered as pending state change
 	// requests.
 	for i := uint64(0); i <= core.ConfigRoundShift+1; i++ {
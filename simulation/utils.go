@@ -75,3 +75,13 @@ func prepareConfigs(
 	}
 	gov.CatchUpWithRound(round)
 }
+
+func newLatencyModel(cfg config.LatencyModel) test.LatencyModel {
+	if cfg.Seed != 0 {
+		return test.NewNormalLatencyModel(cfg.Mean, cfg.Sigma, cfg.Seed)
+	}
+	return &test.NormalLatencyModel{
+		Mean:  cfg.Mean,
+		Sigma: cfg.Sigma,
+	}
+}